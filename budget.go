@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// durationBudgets are limits of durations, zero means no limit.
+type durationBudgets struct {
+	Test    time.Duration
+	Package time.Duration
+	Total   time.Duration
+
+	// Tests are budgets of tests matching glob patterns, which take precedence over Test.
+	Tests []testBudget
+}
+
+type testBudget struct {
+	Pattern string
+	Budget  time.Duration
+	// Source is the location of the budget in the budget file, used in the report.
+	Source string
+}
+
+func (b testBudget) matches(tn parse.TestName) bool {
+	return matchesTestPattern(b.Pattern, tn)
+}
+
+// matchesTestPattern matches the glob pattern against the test name and the full name including the package.
+func matchesTestPattern(pattern string, tn parse.TestName) bool {
+	if ok, _ := path.Match(pattern, tn.String()); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, tn.TestName)
+
+	return ok
+}
+
+// parseBudgetFile reads budgets of tests, one per line, in the format:
+//
+//	# comment
+//	TestIntegration*                 10m
+//	example.com/project/e2e/Test*    0
+//
+// Patterns are matched against the test name and the full name including the package.
+// The first matching pattern wins, and zero disables the limit.
+func parseBudgetFile(r io.Reader, name string) ([]testBudget, error) {
+	var budgets []testBudget
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected pattern and duration, got %q", name, line, text)
+		}
+
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q: %w", name, line, fields[0], err)
+		}
+
+		budget, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid duration: %w", name, line, err)
+		}
+
+		budgets = append(budgets, testBudget{
+			Pattern: fields[0],
+			Budget:  budget,
+			Source:  fmt.Sprintf("%s:%d", name, line),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+
+	return budgets, nil
+}
+
+func readBudgetFile(name string) ([]testBudget, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening budget file: %w", err)
+	}
+	defer f.Close()
+
+	return parseBudgetFile(f, name)
+}
+
+// budgetOf returns the budget of the test, subtests inherit budgets of their parents.
+func (b durationBudgets) budgetOf(tn parse.TestName) (time.Duration, string) {
+	for t, ok := tn, true; ok; t, ok = t.Parent() {
+		for _, budget := range b.Tests {
+			if budget.matches(t) {
+				return budget.Budget, budget.Source
+			}
+		}
+	}
+
+	return b.Test, "-max-test-duration"
+}
+
+type budgetViolation struct {
+	Kind     string
+	Name     string
+	Duration time.Duration
+	Budget   time.Duration
+	Source   string
+}
+
+func (v budgetViolation) String() string {
+	return fmt.Sprintf(
+		"%s %s took %s, budget is %s (%s)",
+		v.Kind,
+		v.Name,
+		v.Duration.Round(time.Millisecond),
+		v.Budget,
+		v.Source,
+	)
+}
+
+// checkBudgets returns executions which took longer than their budgets, the slowest first.
+func checkBudgets(pr parse.ParseResult, budgets durationBudgets) []budgetViolation {
+	var violations []budgetViolation
+
+	if total := pr.End.Sub(pr.Start); budgets.Total > 0 && total > budgets.Total {
+		violations = append(violations, budgetViolation{
+			Kind:     "run",
+			Name:     "total",
+			Duration: total,
+			Budget:   budgets.Total,
+			Source:   "-max-total-duration",
+		})
+	}
+
+	var packageViolations []budgetViolation
+	for pkg, execution := range pr.Packages {
+		duration := execution.Duration()
+		if execution.Elapsed > 0 {
+			duration = execution.Elapsed
+		}
+
+		if budgets.Package > 0 && duration > budgets.Package {
+			packageViolations = append(packageViolations, budgetViolation{
+				Kind:     "package",
+				Name:     pkg,
+				Duration: duration,
+				Budget:   budgets.Package,
+				Source:   "-max-package-duration",
+			})
+		}
+	}
+
+	var testViolations []budgetViolation
+	for tn := range pr.TestRuns {
+		budget, source := budgets.budgetOf(tn)
+		if budget == 0 {
+			continue
+		}
+
+		stats := pr.TestRuns.DurationStats(tn)
+		if stats.Max > budget {
+			testViolations = append(testViolations, budgetViolation{
+				Kind:     "test",
+				Name:     tn.String(),
+				Duration: stats.Max,
+				Budget:   budget,
+				Source:   source,
+			})
+		}
+	}
+
+	for _, v := range [][]budgetViolation{packageViolations, testViolations} {
+		sort.Slice(v, func(i, j int) bool {
+			if v[i].Duration != v[j].Duration {
+				return v[i].Duration > v[j].Duration
+			}
+			return v[i].Name < v[j].Name
+		})
+	}
+
+	return append(append(violations, packageViolations...), testViolations...)
+}
+
+func writeBudgetReport(w io.Writer, violations []budgetViolation) {
+	_, _ = fmt.Fprintf(w, "\nDuration budgets exceeded (%d):\n", len(violations))
+	for _, v := range violations {
+		_, _ = fmt.Fprintf(w, "  %s\n", v)
+	}
+}
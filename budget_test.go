@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBudgets(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestSlow"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestSlow","Elapsed":2}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestIntegration"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestIntegration/case"}
+{"Time":"2024-09-18T21:02:17.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestIntegration/case","Elapsed":3}
+{"Time":"2024-09-18T21:02:17.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestIntegration","Elapsed":3}
+{"Time":"2024-09-18T21:02:17.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestFast"}
+{"Time":"2024-09-18T21:02:17.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestFast","Elapsed":0.1}
+{"Time":"2024-09-18T21:02:17.200000+02:00","Action":"pass","Package":"example.com/pkg","Elapsed":5.2}
+`
+	pr := mustParse(t, input)
+
+	tests, err := parseBudgetFile(strings.NewReader("# integration tests are slow\nTestIntegration*  5s\n"), "budgets.txt")
+	require.NoError(t, err)
+
+	violations := checkBudgets(pr, durationBudgets{
+		Test:    time.Second,
+		Package: 5 * time.Second,
+		Total:   10 * time.Second,
+		Tests:   tests,
+	})
+
+	var reported []string
+	for _, v := range violations {
+		reported = append(reported, v.String())
+	}
+
+	require.Equal(t, []string{
+		"package example.com/pkg took 5.2s, budget is 5s (-max-package-duration)",
+		"test example.com/pkg/TestSlow took 2s, budget is 1s (-max-test-duration)",
+	}, reported, "TestIntegration and its subtests should be exempt")
+}
+
+func TestParseBudgetFile_invalid(t *testing.T) {
+	_, err := parseBudgetFile(strings.NewReader("TestA 5s\nTestB five\n"), "budgets.txt")
+	require.ErrorContains(t, err, "budgets.txt:2")
+}
@@ -3,81 +3,203 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+const (
+	pauseColor        = "rgba(108,122,137,1)"
+	packageSetupColor = "rgba(190, 170, 220, 100)"
+	packageColor      = "rgba(120, 180, 120, 100)"
+	failedColor       = "rgba(255, 0, 0, 100)"
+	skippedColor      = "rgba(180, 180, 180, 100)"
+	buildFailedColor  = "rgba(150, 0, 0, 100)"
 )
 
-func generateCharts(pr ParseResult) []PlotlyChart {
+func generateCharts(pr parse.ParseResult) []PlotlyChart {
 	var charts []PlotlyChart
 
-	testNames := pr.TestNamesOrderedByStart()
+	testsByPackage := map[string][]parse.TestName{}
+	for _, tn := range pr.TestNamesInTreeOrder() {
+		testsByPackage[tn.Package] = append(testsByPackage[tn.Package], tn)
+	}
 
-	for _, tn := range testNames {
-		ch := PlotlyChart{
-			Type:         "bar",
-			Orientation:  "h",
-			Hoverinfo:    "text",
-			Textposition: "inside",
+	for _, pkg := range pr.PackagesOrderedByStart() {
+		if execution, ok := pr.Packages[pkg]; ok {
+			charts = append(charts, generatePackageChart(pr, execution))
 		}
 
-		pause, hasPause := pr.TestPauses.ByTestName(tn)
-		run, hasRun := pr.TestRuns.ByTestName(tn)
-
-		if !hasRun {
-			slog.Debug("Test was not executed", "test", tn)
-			continue
+		for _, tn := range testsByPackage[pkg] {
+			if ch, ok := generateTestChart(pr, tn); ok {
+				charts = append(charts, ch)
+			}
 		}
+	}
 
-		packageNameParts := strings.Split(tn.Package, "/")
+	return charts
+}
 
-		var packageName string
-		if len(packageNameParts) != 0 {
-			packageName = packageNameParts[len(packageNameParts)-1]
-		} else {
-			slog.Warn("Package name is empty", "test", tn.Package)
-		}
+// packageDetailsKey is used instead of the test key for package rows.
+func packageDetailsKey(pkg string) string {
+	return "package:" + pkg
+}
 
-		packageNameFull := fmt.Sprintf("%s.%s", packageName, tn.TestName)
-		y := packageNameFull
+func generatePackageChart(pr parse.ParseResult, pkg parse.PackageExecution) PlotlyChart {
+	ch := PlotlyChart{
+		Type:         "bar",
+		Orientation:  "h",
+		Hoverinfo:    "text",
+		Textposition: "inside",
+	}
 
-		if !run.Passed {
-			y += " (failed)"
+	if pkg.BuildFailed {
+		// failed builds have no duration, so the row spans the whole run to not get lost
+		width := pr.End.Sub(pr.Start)
+		if width <= 0 {
+			width = time.Second
 		}
 
-		if hasPause {
-			startAfter := pause.Start.Sub(pr.Start)
-			duration := pause.Duration()
+		ch.Add(
+			fmt.Sprintf("%s BUILD FAILED", pkg.Package),
+			pkg.Package+" (build failed)",
+			packageDetailsKey(pkg.Package),
+			0,
+			width,
+			buildFailedColor,
+		)
+		return ch
+	}
 
-			slog.Debug("Test was paused", "startAfter", startAfter, "duration", duration, "test", tn)
+	y := pkg.Package
+	color := packageColor
+	if pkg.Status == parse.TestStatusFailed {
+		y += " (failed)"
+		color = failedColor
+	}
 
-			ch.Add(
-				fmt.Sprintf("%s PAUSE (%s)", packageNameFull, duration.Round(time.Millisecond).String()),
-				y,
-				startAfter,
-				duration,
-				"rgba(108,122,137,1)",
-			)
+	setupDuration := pkg.SetupDuration()
+
+	ch.Add(
+		fmt.Sprintf("%s SETUP (%s)", pkg.Package, setupDuration.Round(time.Millisecond)),
+		y,
+		packageDetailsKey(pkg.Package),
+		pkg.Start.Sub(pr.Start),
+		setupDuration,
+		packageSetupColor,
+	)
+
+	if !pkg.FirstTestStart.IsZero() {
+		duration := pkg.Duration()
+		if pkg.Elapsed > 0 {
+			duration = pkg.Elapsed
 		}
 
-		{
-			startAfter := run.Start.Sub(pr.Start)
-			duration := run.Duration()
+		ch.Add(
+			fmt.Sprintf("%s PACKAGE (%s)", pkg.Package, duration.Round(time.Millisecond)),
+			y,
+			packageDetailsKey(pkg.Package),
+			pkg.FirstTestStart.Sub(pr.Start),
+			pkg.End.Sub(pkg.FirstTestStart),
+			color,
+		)
+	}
 
-			slog.Debug("Test was executed", "startAfter", startAfter, "duration", duration, "test", tn)
+	slog.Debug("Package chart", "package", pkg.Package, "chart", ch)
+
+	return ch
+}
+
+func generateTestChart(pr parse.ParseResult, tn parse.TestName) (PlotlyChart, bool) {
+	ch := PlotlyChart{
+		Type:         "bar",
+		Orientation:  "h",
+		Hoverinfo:    "text",
+		Textposition: "inside",
+	}
+
+	runs, hasRun := pr.TestRuns.ByTestName(tn)
+
+	if !hasRun {
+		slog.Debug("Test was not executed", "test", tn)
+		return PlotlyChart{}, false
+	}
+
+	packageNameParts := strings.Split(tn.Package, "/")
+
+	var packageName string
+	if len(packageNameParts) != 0 {
+		packageName = packageNameParts[len(packageNameParts)-1]
+	} else {
+		slog.Warn("Package name is empty", "test", tn.Package)
+	}
+
+	packageNameFull := fmt.Sprintf("%s.%s", packageName, tn.TestName)
+	y := packageNameFull
+
+	flaky := flakinessOf(runs)
+
+	if flaky.Flaky() {
+		y += " (flaky)"
+	} else if pr.TestFailed(tn) {
+		y += " (failed)"
+	} else if !slices.ContainsFunc(runs, func(run parse.TestExecution) bool { return run.Status != parse.TestStatusSkipped }) {
+		y += " (skipped)"
+	}
+
+	stats := pr.TestRuns.DurationStats(tn)
+
+	for _, run := range runs {
+		label := packageNameFull
+		if len(runs) > 1 {
+			label = fmt.Sprintf("%s #%d", packageNameFull, run.Attempt)
+		}
+
+		for _, segment := range run.Segments {
+			startAfter := segment.Start.Sub(pr.Start)
+			duration := segment.Duration()
+
+			if segment.Kind == parse.SegmentKindPause {
+				slog.Debug("Test was paused", "startAfter", startAfter, "duration", duration, "test", tn, "attempt", run.Attempt)
+
+				ch.Add(
+					fmt.Sprintf("%s PAUSE (%s)", label, duration.Round(time.Millisecond).String()),
+					y,
+					tn.String(),
+					startAfter,
+					duration,
+					pauseColor,
+				)
+				continue
+			}
+
+			slog.Debug("Test was executed", "startAfter", startAfter, "duration", duration, "test", tn, "attempt", run.Attempt)
+
+			text := fmt.Sprintf("%s RUN (%s)", label, run.Duration().Round(time.Millisecond))
+			if run.HasDurationDiscrepancy() {
+				text += fmt.Sprintf(" ⚠ timestamps: %s", run.TimestampDuration().Round(time.Millisecond))
+			}
+			if stats.Attempts > 1 {
+				text += fmt.Sprintf(" [%s]", stats)
+			}
+			if flaky.Flaky() {
+				text += fmt.Sprintf(" ⚠ flaky: %s", flaky)
+			}
 
 			ch.Add(
-				fmt.Sprintf("%s RUN (%s)", packageNameFull, duration.Round(time.Millisecond)),
+				text,
 				y,
+				tn.String(),
 				startAfter,
 				duration,
 				durationToRgb(run, pr.MaxDuration),
 			)
 		}
-
-		slog.Debug("PlotlyChart", "chart", ch)
-
-		charts = append(charts, ch)
 	}
 
-	return charts
+	slog.Debug("PlotlyChart", "chart", ch)
+
+	return ch, true
 }
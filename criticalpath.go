@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+const (
+	// criticalPathGap is the longest gap between the end of an execution and the start of the next one
+	// for which the next one is considered to be waiting for the previous one.
+	criticalPathGap = 20 * time.Millisecond
+	// criticalPathPackageGap is longer, because test binaries are started after they are built.
+	criticalPathPackageGap = 500 * time.Millisecond
+
+	criticalPathKey   = "critical-path"
+	criticalPathColor = "rgba(0, 0, 0, 1)"
+	onCriticalPath    = "On the critical path."
+)
+
+// criticalPathStep is a package or a test execution which determined the total duration of the run.
+type criticalPathStep struct {
+	// Key is the key of the row in the chart.
+	Key     string
+	Name    string
+	Attempt int
+	Start   time.Time
+	End     time.Time
+	// Depth is the nesting level, tests are nested in packages and subtests in their parents.
+	Depth int
+}
+
+func (s criticalPathStep) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// pathNode is an execution which can be a part of the critical path.
+type pathNode struct {
+	step criticalPathStep
+	// released is when the execution could start running, for parallel tests it's when they were continued
+	released time.Time
+
+	children []*parse.TestNode
+}
+
+// criticalPath returns the chain of executions which determined the wall time of the run.
+// The path is built backwards: starting from the execution which finished last, each step is preceded
+// by the execution which finished right before the step started (and the step was waiting for it).
+// Tests on the path are expanded to their tests and subtests in the same way.
+func criticalPath(pr parse.ParseResult) []criticalPathStep {
+	var packages []pathNode
+	for _, pkg := range pr.PackagesOrderedByStart() {
+		execution, ok := pr.Packages[pkg]
+		if !ok || execution.Start.IsZero() || execution.End.IsZero() {
+			continue
+		}
+
+		packages = append(packages, pathNode{
+			step: criticalPathStep{
+				Key:   packageDetailsKey(pkg),
+				Name:  pkg,
+				Start: execution.Start,
+				End:   execution.End,
+			},
+			released: execution.Start,
+		})
+	}
+
+	rootsByPackage := map[string][]*parse.TestNode{}
+	for _, root := range pr.TestTree() {
+		rootsByPackage[root.Test.Package] = append(rootsByPackage[root.Test.Package], root)
+	}
+
+	var steps []criticalPathStep
+	for _, pkg := range chainOf(packages, criticalPathPackageGap) {
+		steps = append(steps, pkg.step)
+		steps = append(steps, testsCriticalPath(pr, rootsByPackage[pkg.step.Name], 1)...)
+	}
+
+	return steps
+}
+
+func testsCriticalPath(pr parse.ParseResult, tests []*parse.TestNode, depth int) []criticalPathStep {
+	var nodes []pathNode
+	for _, test := range tests {
+		runs, _ := pr.TestRuns.ByTestName(test.Test)
+		for _, run := range runs {
+			if run.End.IsZero() || len(run.Segments) == 0 {
+				continue
+			}
+
+			nodes = append(nodes, pathNode{
+				step: criticalPathStep{
+					Key:     test.Test.String(),
+					Name:    test.Test.String(),
+					Attempt: run.Attempt,
+					Start:   run.Start,
+					End:     run.End,
+					Depth:   depth,
+				},
+				released: run.Segments[len(run.Segments)-1].Start,
+				children: test.Children,
+			})
+		}
+	}
+
+	var steps []criticalPathStep
+	for _, node := range chainOf(nodes, criticalPathGap) {
+		steps = append(steps, node.step)
+		steps = append(steps, testsCriticalPath(pr, node.children, depth+1)...)
+	}
+
+	return steps
+}
+
+// chainOf returns nodes which were waiting for each other, ending with the node which finished last.
+func chainOf(nodes []pathNode, gap time.Duration) []pathNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	last := 0
+	for i, node := range nodes {
+		if node.step.End.After(nodes[last].step.End) {
+			last = i
+		}
+	}
+
+	chain := []pathNode{nodes[last]}
+	used := map[int]bool{last: true}
+
+	for {
+		current := chain[0]
+
+		previous := -1
+		for i, node := range nodes {
+			if used[i] || node.step.End.After(current.released.Add(time.Millisecond)) {
+				continue
+			}
+			if previous == -1 || node.step.End.After(nodes[previous].step.End) {
+				previous = i
+			}
+		}
+
+		if previous == -1 || current.released.Sub(nodes[previous].step.End) > gap {
+			return chain
+		}
+
+		chain = append([]pathNode{nodes[previous]}, chain...)
+		used[previous] = true
+	}
+}
+
+// highlightCriticalPath outlines bars of executions on the critical path.
+func highlightCriticalPath(charts []PlotlyChart, path []criticalPathStep) {
+	onPath := map[string]bool{}
+	for _, step := range path {
+		onPath[step.Key] = true
+	}
+
+	for i := range charts {
+		ch := &charts[i]
+		if len(ch.Customdata) == 0 || !onPath[ch.Customdata[0]] {
+			continue
+		}
+
+		ch.Marker.Line = &plotlyLine{}
+		for _, color := range ch.Marker.Color {
+			width := 2.0
+			if color == pauseColor || color == previousRunColor {
+				width = 0
+			}
+			ch.Marker.Line.Color = append(ch.Marker.Line.Color, criticalPathColor)
+			ch.Marker.Line.Width = append(ch.Marker.Line.Width, width)
+		}
+	}
+}
+
+func criticalPathLines(pr parse.ParseResult, path []criticalPathStep) []string {
+	wall := pr.End.Sub(pr.Start)
+
+	var lines []string
+	for _, step := range path {
+		name := step.Name
+		if step.Attempt > 1 {
+			name += fmt.Sprintf(" #%d", step.Attempt)
+		}
+
+		share := 0.0
+		if wall > 0 {
+			share = float64(step.Duration()) / float64(wall) * 100
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"%*s%s %s (%.0f%%)",
+			step.Depth*2, "",
+			name,
+			step.Duration().Round(time.Millisecond),
+			share,
+		))
+	}
+
+	return lines
+}
+
+// addCriticalPathDetails adds the list of the critical path, presented after clicking the "Critical path" button.
+func addCriticalPathDetails(details map[string]testDetails, pr parse.ParseResult, path []criticalPathStep) {
+	if len(path) == 0 {
+		return
+	}
+
+	for _, step := range path {
+		d, ok := details[step.Key]
+		if !ok || strings.Contains(d.Summary, onCriticalPath) {
+			continue
+		}
+		d.Summary = strings.TrimSpace(d.Summary + " " + onCriticalPath)
+		details[step.Key] = d
+	}
+
+	details[criticalPathKey] = testDetails{
+		Name: "Critical path",
+		Summary: fmt.Sprintf(
+			"Executions which determined the total duration of %s, with their share of it.",
+			pr.End.Sub(pr.Start).Round(time.Millisecond),
+		),
+		Attempts: []testAttempt{{Attempt: 1, Output: criticalPathLines(pr, path)}},
+	}
+}
+
+func writeCriticalPath(w io.Writer, pr parse.ParseResult, path []criticalPathStep) {
+	if len(path) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nCritical path (%s total):\n", pr.End.Sub(pr.Start).Round(time.Millisecond))
+	for _, line := range criticalPathLines(pr, path) {
+		_, _ = fmt.Fprintf(w, "  %s\n", line)
+	}
+}
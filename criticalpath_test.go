@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCriticalPath(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/other"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/other","Test":"TestOther"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/a"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/b"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/a"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/other","Test":"TestOther"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/other"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/b"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:16.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:16.000000+02:00","Action":"pass","Package":"example.com/pkg"}
+`
+	pr := mustParse(t, input)
+	path := criticalPath(pr)
+
+	var keys []string
+	for _, step := range path {
+		keys = append(keys, step.Key)
+	}
+	require.Equal(t, []string{
+		"package:example.com/pkg",
+		"example.com/pkg/TestA",
+		"example.com/pkg/TestA/b",
+		"example.com/pkg/TestB",
+	}, keys, "TestOther and TestA/a were not delaying anything")
+
+	require.Equal(t, []string{
+		"example.com/pkg 4s (100%)",
+		"  example.com/pkg/TestA 2s (50%)",
+		"    example.com/pkg/TestA/b 2s (50%)",
+		"  example.com/pkg/TestB 2s (50%)",
+	}, criticalPathLines(pr, path))
+
+	charts := generateCharts(pr)
+	highlightCriticalPath(charts, path)
+
+	for _, ch := range charts {
+		onPath := ch.Customdata[0] != "example.com/other/TestOther" && ch.Customdata[0] != "example.com/pkg/TestA/a" &&
+			ch.Customdata[0] != "package:example.com/other"
+		require.Equal(t, onPath, ch.Marker.Line != nil, ch.Customdata[0])
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+const (
+	// previousRunColor is used for bars of the old run, which are presented behind bars of the new run.
+	previousRunColor = "rgba(200, 200, 200, 100)"
+	slowerColor      = "rgba(230, 120, 0, 100)"
+	fasterColor      = "rgba(0, 160, 80, 100)"
+)
+
+// testDiff compares executions of a test between two runs.
+type testDiff struct {
+	Test parse.TestName
+
+	InOld, InNew bool
+
+	OldDuration, NewDuration time.Duration
+	OldStatus, NewStatus     parse.TestStatus
+}
+
+func (d testDiff) Change() time.Duration {
+	return d.NewDuration - d.OldDuration
+}
+
+// RelativeChange returns the change as a fraction of the old duration.
+func (d testDiff) RelativeChange() float64 {
+	if d.OldDuration == 0 {
+		return 0
+	}
+
+	return float64(d.Change()) / float64(d.OldDuration)
+}
+
+type runsDiff struct {
+	Slower        []testDiff
+	Faster        []testDiff
+	Added         []testDiff
+	Removed       []testDiff
+	StatusChanged []testDiff
+
+	OldDuration, NewDuration time.Duration
+}
+
+// diffThreshold decides which duration changes are significant.
+// Changes have to exceed both thresholds, so tiny tests don't flood the report.
+type diffThreshold struct {
+	Relative float64
+	Absolute time.Duration
+}
+
+func (t diffThreshold) exceeded(d testDiff) bool {
+	change := d.Change()
+	if change < 0 {
+		change = -change
+	}
+
+	relative := d.RelativeChange()
+	if relative < 0 {
+		relative = -relative
+	}
+
+	return change > t.Absolute && (d.OldDuration == 0 || relative > t.Relative)
+}
+
+// testSummary returns the median duration and the overall status of all attempts of a test.
+func testSummary(pr parse.ParseResult, tn parse.TestName) (time.Duration, parse.TestStatus) {
+	runs, _ := pr.TestRuns.ByTestName(tn)
+
+	status := parse.TestStatusSkipped
+	for _, run := range runs {
+		if run.Status != parse.TestStatusSkipped {
+			status = run.Status
+		}
+	}
+	if pr.TestFailed(tn) {
+		status = parse.TestStatusFailed
+	}
+
+	return pr.TestRuns.DurationStats(tn).Median, status
+}
+
+func diffRuns(before, after parse.ParseResult, threshold diffThreshold) runsDiff {
+	diffs := map[parse.TestName]testDiff{}
+
+	for tn := range before.TestRuns {
+		d := diffs[tn]
+		d.Test = tn
+		d.InOld = true
+		d.OldDuration, d.OldStatus = testSummary(before, tn)
+		diffs[tn] = d
+	}
+	for tn := range after.TestRuns {
+		d := diffs[tn]
+		d.Test = tn
+		d.InNew = true
+		d.NewDuration, d.NewStatus = testSummary(after, tn)
+		diffs[tn] = d
+	}
+
+	result := runsDiff{
+		OldDuration: before.End.Sub(before.Start),
+		NewDuration: after.End.Sub(after.Start),
+	}
+
+	for _, d := range diffs {
+		switch {
+		case !d.InOld:
+			result.Added = append(result.Added, d)
+			continue
+		case !d.InNew:
+			result.Removed = append(result.Removed, d)
+			continue
+		}
+
+		if d.OldStatus != d.NewStatus {
+			result.StatusChanged = append(result.StatusChanged, d)
+		}
+
+		// durations of skipped tests are not meaningful
+		if d.OldStatus == parse.TestStatusSkipped || d.NewStatus == parse.TestStatusSkipped || !threshold.exceeded(d) {
+			continue
+		}
+
+		if d.Change() > 0 {
+			result.Slower = append(result.Slower, d)
+		} else {
+			result.Faster = append(result.Faster, d)
+		}
+	}
+
+	byName := func(diffs []testDiff) {
+		sort.Slice(diffs, func(i, j int) bool {
+			return diffs[i].Test.String() < diffs[j].Test.String()
+		})
+	}
+	byChange := func(diffs []testDiff) {
+		sort.SliceStable(diffs, func(i, j int) bool {
+			ci, cj := diffs[i].Change(), diffs[j].Change()
+			return max(ci, -ci) > max(cj, -cj)
+		})
+	}
+
+	for _, diffs := range [][]testDiff{result.Slower, result.Faster, result.Added, result.Removed, result.StatusChanged} {
+		byName(diffs)
+	}
+	byChange(result.Slower)
+	byChange(result.Faster)
+
+	return result
+}
+
+func formatChange(d testDiff) string {
+	change := d.Change().Round(time.Millisecond).String()
+	if d.Change() > 0 {
+		change = "+" + change
+	}
+
+	if d.OldDuration == 0 {
+		return change
+	}
+
+	return fmt.Sprintf("%s, %+.0f%%", change, d.RelativeChange()*100)
+}
+
+func writeDiffReport(w io.Writer, diff runsDiff) {
+	_, _ = fmt.Fprintf(
+		w,
+		"Total: %s → %s\n",
+		diff.OldDuration.Round(time.Millisecond),
+		diff.NewDuration.Round(time.Millisecond),
+	)
+
+	section := func(title string, diffs []testDiff, format func(testDiff) string) {
+		if len(diffs) == 0 {
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, "\n%s (%d):\n", title, len(diffs))
+		for _, d := range diffs {
+			_, _ = fmt.Fprintf(w, "  %s  %s\n", d.Test, format(d))
+		}
+	}
+
+	durations := func(d testDiff) string {
+		return fmt.Sprintf(
+			"%s → %s (%s)",
+			d.OldDuration.Round(time.Millisecond),
+			d.NewDuration.Round(time.Millisecond),
+			formatChange(d),
+		)
+	}
+
+	section("Slower", diff.Slower, durations)
+	section("Faster", diff.Faster, durations)
+	section("Status changed", diff.StatusChanged, func(d testDiff) string {
+		return fmt.Sprintf("%s → %s", d.OldStatus, d.NewStatus)
+	})
+	section("New tests", diff.Added, func(d testDiff) string {
+		return fmt.Sprintf("%s %s", d.NewDuration.Round(time.Millisecond), d.NewStatus)
+	})
+	section("Removed tests", diff.Removed, func(d testDiff) string {
+		return fmt.Sprintf("%s %s", d.OldDuration.Round(time.Millisecond), d.OldStatus)
+	})
+
+	if len(diff.Slower)+len(diff.Faster)+len(diff.StatusChanged)+len(diff.Added)+len(diff.Removed) == 0 {
+		_, _ = fmt.Fprintln(w, "\nNo significant changes.")
+	}
+}
+
+// generateDiffCharts overlays the old run on the new one: bars of the old run are presented behind
+// thinner bars of the new run in the same row. Tests which were removed are presented at the end.
+func generateDiffCharts(before, after parse.ParseResult, diff runsDiff) []PlotlyChart {
+	oldCharts := map[string]PlotlyChart{}
+	var oldOrder []string
+	for _, ch := range generateCharts(before) {
+		if len(ch.Customdata) == 0 {
+			continue
+		}
+		oldCharts[ch.Customdata[0]] = ch
+		oldOrder = append(oldOrder, ch.Customdata[0])
+	}
+
+	changes := map[string]testDiff{}
+	for _, d := range slices.Concat(diff.Slower, diff.Faster) {
+		changes[d.Test.String()] = d
+	}
+
+	var charts []PlotlyChart
+	seen := map[string]bool{}
+
+	for _, ch := range generateCharts(after) {
+		if len(ch.Customdata) == 0 {
+			continue
+		}
+		key := ch.Customdata[0]
+		seen[key] = true
+
+		merged := PlotlyChart{
+			Type:         ch.Type,
+			Orientation:  ch.Orientation,
+			Hoverinfo:    ch.Hoverinfo,
+			Textposition: ch.Textposition,
+		}
+
+		if oldChart, ok := oldCharts[key]; ok {
+			addPreviousRun(&merged, oldChart, ch.Y[0])
+		}
+
+		d, changed := changes[key]
+		for i := range ch.X {
+			text := ch.Text[i]
+			color := ch.Marker.Color[i]
+			if changed && color != pauseColor {
+				text += fmt.Sprintf(" (%s vs previous run)", formatChange(d))
+				if color != failedColor {
+					color = fasterColor
+					if d.Change() > 0 {
+						color = slowerColor
+					}
+				}
+			}
+
+			merged.Y = append(merged.Y, ch.Y[i])
+			merged.Customdata = append(merged.Customdata, key)
+			merged.X = append(merged.X, ch.X[i])
+			merged.Base = append(merged.Base, ch.Base[i])
+			merged.Text = append(merged.Text, text)
+			merged.Width = append(merged.Width, 0.5)
+			merged.Marker.Color = append(merged.Marker.Color, color)
+		}
+
+		charts = append(charts, merged)
+	}
+
+	for _, key := range oldOrder {
+		if seen[key] {
+			continue
+		}
+
+		oldChart := oldCharts[key]
+		removed := PlotlyChart{
+			Type:         oldChart.Type,
+			Orientation:  oldChart.Orientation,
+			Hoverinfo:    oldChart.Hoverinfo,
+			Textposition: oldChart.Textposition,
+		}
+		addPreviousRun(&removed, oldChart, oldChart.Y[0]+" (removed)")
+		charts = append(charts, removed)
+	}
+
+	return charts
+}
+
+func addPreviousRun(ch *PlotlyChart, previous PlotlyChart, y string) {
+	for i := range previous.X {
+		ch.Y = append(ch.Y, y)
+		ch.Customdata = append(ch.Customdata, previous.Customdata[i])
+		ch.X = append(ch.X, previous.X[i])
+		ch.Base = append(ch.Base, previous.Base[i])
+		ch.Text = append(ch.Text, "PREVIOUS RUN: "+previous.Text[i])
+		ch.Width = append(ch.Width, 0.9)
+		ch.Marker.Color = append(ch.Marker.Color, previousRunColor)
+	}
+}
+
+func parseFile(path string) (parse.ParseResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return parse.ParseResult{}, fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	pr, err := parse.Parse(f, parse.Options{KeepAllExecutions: true})
+	if err != nil {
+		return parse.ParseResult{}, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return pr, nil
+}
+
+// runDiff implements "vgt diff old.json new.json" and returns the exit code.
+func runDiff(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: vgt diff [flags] old.json new.json")
+		flags.PrintDefaults()
+	}
+
+	var threshold diffThreshold
+	var relativeThreshold float64
+	var textOnly bool
+
+	flags.BoolVar(&debug, "debug", false, "enable debug mode")
+	flags.BoolVar(&printHTML, "print-html", false, "print html to stdout instead of opening browser")
+	flags.BoolVar(&keepRunning, "keep-running", false, "keep browser running after page was opened")
+	flags.BoolVar(&textOnly, "text", false, "print only the text report, without opening browser")
+	flags.Float64Var(&relativeThreshold, "threshold", 20, "minimal change of test duration (in percent) to be reported")
+	flags.DurationVar(&threshold.Absolute, "min-change", 50*time.Millisecond, "minimal change of test duration to be reported")
+	flags.DurationVar(&testDurationCutoffDuration, "duration-cutoff", 100*time.Microsecond, "threshold for test duration cutoff, under which tests are not shown in the chart")
+	_ = flags.Parse(args)
+
+	threshold.Relative = relativeThreshold / 100
+
+	setupLogger()
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	before, err := parseFile(flags.Arg(0))
+	if err != nil {
+		slog.Error("Error reading old run", "err", err)
+		return 1
+	}
+	after, err := parseFile(flags.Arg(1))
+	if err != nil {
+		slog.Error("Error reading new run", "err", err)
+		return 1
+	}
+
+	diff := diffRuns(before, after, threshold)
+
+	if !printHTML {
+		writeDiffReport(os.Stdout, diff)
+	}
+	if textOnly {
+		return 0
+	}
+
+	newCharted := after.WithDurationCutoff(testDurationCutoffDuration)
+	charts := generateDiffCharts(before.WithDurationCutoff(testDurationCutoffDuration), newCharted, diff)
+
+	if printHTML {
+		html, err := render(newCharted, charts, parallelismOptions{}, false, false)
+		if err != nil {
+			slog.Error("Error rendering html", "err", err)
+			return 1
+		}
+		_, _ = os.Stdout.Write([]byte(html))
+		return 0
+	}
+
+	timeline := newLiveTimeline()
+	timeline.FinishWithCharts(newCharted, charts)
+	serveHTML(ctx, timeline)
+
+	return 0
+}
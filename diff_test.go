@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+func TestDiffRuns(t *testing.T) {
+	oldRun := mustParse(t, `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestSlower"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestSlower","Elapsed":1}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestFaster"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestFaster","Elapsed":1}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestNoise"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestNoise","Elapsed":1}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestBroken","Elapsed":1}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestRemoved"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestRemoved","Elapsed":1}
+`)
+	newRun := mustParse(t, `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestSlower"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestSlower","Elapsed":2}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestFaster"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestFaster","Elapsed":0.5}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestNoise"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestNoise","Elapsed":1.1}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestBroken","Elapsed":1}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestAdded"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestAdded","Elapsed":1}
+`)
+
+	diff := diffRuns(oldRun, newRun, diffThreshold{Relative: 0.2, Absolute: 50 * time.Millisecond})
+
+	names := func(diffs []testDiff) []string {
+		var names []string
+		for _, d := range diffs {
+			names = append(names, d.Test.TestName)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"TestSlower"}, names(diff.Slower))
+	require.Equal(t, []string{"TestFaster"}, names(diff.Faster))
+	require.Equal(t, []string{"TestAdded"}, names(diff.Added))
+	require.Equal(t, []string{"TestRemoved"}, names(diff.Removed))
+	require.Equal(t, []string{"TestBroken"}, names(diff.StatusChanged))
+	require.Equal(t, parse.TestStatusFailed, diff.StatusChanged[0].NewStatus)
+
+	charts := generateDiffCharts(oldRun, newRun, diff)
+	require.Len(t, charts, 6, "5 tests of the new run and the removed test")
+
+	for _, ch := range charts {
+		if ch.Customdata[0] != "example.com/pkg/TestSlower" {
+			continue
+		}
+
+		require.Equal(t, []string{previousRunColor, slowerColor}, ch.Marker.Color)
+		require.Equal(t, []float64{0.9, 0.5}, ch.Width)
+	}
+	require.Equal(t, []string{previousRunColor}, charts[len(charts)-1].Marker.Color)
+}
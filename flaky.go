@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+const flakyTestsKey = "flaky-tests"
+
+// flakiness counts executions of a test, skipped executions are not counted.
+type flakiness struct {
+	Executions int
+	Failures   int
+}
+
+func flakinessOf(runs []parse.TestExecution) flakiness {
+	var f flakiness
+	for _, run := range runs {
+		switch run.Status {
+		case parse.TestStatusPassed:
+			f.Executions++
+		case parse.TestStatusFailed:
+			f.Executions++
+			f.Failures++
+		}
+	}
+
+	return f
+}
+
+// Flaky is true if the test both passed and failed.
+func (f flakiness) Flaky() bool {
+	return f.Failures > 0 && f.Failures < f.Executions
+}
+
+// Rate is the fraction of failed executions.
+func (f flakiness) Rate() float64 {
+	if f.Executions == 0 {
+		return 0
+	}
+
+	return float64(f.Failures) / float64(f.Executions)
+}
+
+func (f flakiness) String() string {
+	return fmt.Sprintf("failed %d of %d executions (%.0f%%)", f.Failures, f.Executions, f.Rate()*100)
+}
+
+type flakyTest struct {
+	Test parse.TestName
+	flakiness
+}
+
+// findFlakyTests returns tests with different statuses in different executions, the most flaky first.
+// Executions can come from go test -count, re-runs of failed tests, or multiple inputs.
+// Tests which failed only because their subtests are flaky are not returned, the subtests are.
+func findFlakyTests(pr parse.ParseResult) []flakyTest {
+	flaky := map[parse.TestName]flakiness{}
+	for tn, runs := range pr.TestRuns {
+		if f := flakinessOf(runs); f.Flaky() {
+			flaky[tn] = f
+		}
+	}
+
+	for tn := range flaky {
+		for parent, ok := tn.Parent(); ok; parent, ok = parent.Parent() {
+			delete(flaky, parent)
+		}
+	}
+
+	tests := make([]flakyTest, 0, len(flaky))
+	for tn, f := range flaky {
+		tests = append(tests, flakyTest{Test: tn, flakiness: f})
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Rate() != tests[j].Rate() {
+			return tests[i].Rate() > tests[j].Rate()
+		}
+		return tests[i].Test.String() < tests[j].Test.String()
+	})
+
+	return tests
+}
+
+// addFlakyDetails adds the list of flaky tests, presented after clicking the "Flaky tests" button.
+func addFlakyDetails(details map[string]testDetails, flaky []flakyTest) {
+	if len(flaky) == 0 {
+		return
+	}
+
+	var lines []string
+	for _, test := range flaky {
+		lines = append(lines, fmt.Sprintf("%s %s", test.Test, test.flakiness))
+
+		if d, ok := details[test.Test.String()]; ok {
+			d.Summary = strings.TrimSpace(fmt.Sprintf("%s Flaky: %s.", d.Summary, test.flakiness))
+			details[test.Test.String()] = d
+		}
+	}
+
+	details[flakyTestsKey] = testDetails{
+		Name:     fmt.Sprintf("Flaky tests (%d)", len(flaky)),
+		Summary:  "Tests which both passed and failed, the most flaky first.",
+		Attempts: []testAttempt{{Attempt: 1, Output: lines}},
+	}
+}
+
+func writeFlakyReport(w io.Writer, flaky []flakyTest) {
+	if len(flaky) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nFlaky tests (%d):\n", len(flaky))
+	for _, test := range flaky {
+		_, _ = fmt.Fprintf(w, "  %-70s %s\n", test.Test, test.flakiness)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+func TestFindFlakyTests(t *testing.T) {
+	// output of go test -count=2
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"fail","Package":"example.com/pkg"}
+`
+	pr := mustParse(t, input)
+
+	flaky := findFlakyTests(pr)
+	require.Len(t, flaky, 1, "TestA failed only because of its subtest, and TestB always fails")
+	require.Equal(t, "example.com/pkg/TestA/sub", flaky[0].Test.String())
+	require.Equal(t, 0.5, flaky[0].Rate())
+	require.Equal(t, "failed 1 of 2 executions (50%)", flaky[0].flakiness.String())
+
+	details := testDetailsByKey(pr)
+	addFlakyDetails(details, flaky)
+	require.Contains(t, details["example.com/pkg/TestA/sub"].Summary, "Flaky: failed 1 of 2 executions (50%).")
+	require.Equal(t, []string{"example.com/pkg/TestA/sub failed 1 of 2 executions (50%)"}, details[flakyTestsKey].Attempts[0].Output)
+}
+
+func TestFindFlakyTests_multiple_inputs(t *testing.T) {
+	dir := t.TempDir()
+
+	first := filepath.Join(dir, "run1.json")
+	require.NoError(t, os.WriteFile(first, []byte(`{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"fail","Package":"example.com/pkg"}`), 0o644))
+
+	second := filepath.Join(dir, "run2.json")
+	require.NoError(t, os.WriteFile(second, []byte(`{"Time":"2024-09-18T22:00:00.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T22:00:00.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T22:00:00.200000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T22:00:00.300000+02:00","Action":"pass","Package":"example.com/pkg"}
+`), 0o644))
+
+	var runs []parse.ParseResult
+	for _, name := range []string{first, second} {
+		run, err := parseFile(name)
+		require.NoError(t, err)
+		runs = append(runs, run)
+	}
+	pr := parse.Merge(runs...)
+
+	flaky := findFlakyTests(pr)
+	require.Len(t, flaky, 1)
+	require.Equal(t, "example.com/pkg/TestA", flaky[0].Test.String())
+	require.Equal(t, "failed 1 of 2 executions (50%)", flaky[0].flakiness.String())
+
+	require.Equal(t, 300*time.Millisecond, pr.End.Sub(pr.Start), "time between runs should not be counted")
+	require.GreaterOrEqual(t, pr.Packages["example.com/pkg"].SetupDuration(), time.Duration(0))
+
+	html, err := render(pr, generateCharts(pr), parallelismOptions{}, false, false)
+	require.NoError(t, err)
+	require.Contains(t, html, "CONCURRENCY = null;", "merged runs overlap, so their parallelism is not analysed")
+}
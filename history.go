@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// historyFileName is the file in the history directory to which runs are appended, one JSON object per line.
+const historyFileName = "runs.jsonl"
+
+// historyRun is a summary of a run stored in the history.
+type historyRun struct {
+	Time     time.Time     `json:"time"`
+	Commit   string        `json:"commit,omitempty"`
+	Branch   string        `json:"branch,omitempty"`
+	Duration time.Duration `json:"duration"`
+
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+
+	Packages map[string]time.Duration `json:"packages"`
+	Tests    []historyTest            `json:"tests"`
+}
+
+type historyTest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	// Duration is the median duration of all attempts.
+	Duration time.Duration `json:"duration"`
+	Failed   bool          `json:"failed,omitempty"`
+}
+
+func (t historyTest) Name() parse.TestName {
+	return parse.TestName{Package: t.Package, TestName: t.Test}
+}
+
+func newHistoryRun(pr parse.ParseResult, commit, branch string) historyRun {
+	run := historyRun{
+		Time:     pr.Start,
+		Commit:   commit,
+		Branch:   branch,
+		Duration: pr.End.Sub(pr.Start),
+		Passed:   pr.Counts.Passed,
+		Failed:   pr.Counts.Failed,
+		Skipped:  pr.Counts.Skipped,
+		Packages: map[string]time.Duration{},
+	}
+	if run.Time.IsZero() {
+		run.Time = time.Now()
+	}
+
+	for pkg, execution := range pr.Packages {
+		duration := execution.Duration()
+		if execution.Elapsed > 0 {
+			duration = execution.Elapsed
+		}
+		run.Packages[pkg] = duration
+	}
+
+	for _, tn := range pr.TestNamesInTreeOrder() {
+		duration, status := testSummary(pr, tn)
+		run.Tests = append(run.Tests, historyTest{
+			Package:  tn.Package,
+			Test:     tn.TestName,
+			Duration: duration,
+			Failed:   status == parse.TestStatusFailed,
+		})
+	}
+
+	return run
+}
+
+// gitMetadata returns the commit and the branch of the repository in the working directory.
+// Both are empty if they are not available, for example when tests are not run in a git repository.
+func gitMetadata() (commit string, branch string) {
+	git := func(args ...string) string {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			slog.Debug("Error running git", "args", args, "err", err)
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	commit = git("rev-parse", "HEAD")
+	branch = git("rev-parse", "--abbrev-ref", "HEAD")
+	if branch == "HEAD" {
+		// detached HEAD, which is common in CI
+		branch = ""
+	}
+
+	return commit, branch
+}
+
+func appendHistory(dir string, run historyRun) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating history directory: %w", err)
+	}
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("error marshalling run: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, historyFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing history: %w", err)
+	}
+
+	return nil
+}
+
+// readHistory returns runs stored in the history directory, the oldest first.
+func readHistory(dir string) ([]historyRun, error) {
+	f, err := os.Open(filepath.Join(dir, historyFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []historyRun
+
+	scanner := bufio.NewScanner(f)
+	// runs with many tests are stored in long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var run historyRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", historyFileName, line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Time.Before(runs[j].Time)
+	})
+
+	return runs, nil
+}
+
+// testTrend contains durations of a test in runs from the history, zero if the test was not present in the run.
+type testTrend struct {
+	Test      parse.TestName
+	Durations []time.Duration
+}
+
+// First and Last return durations from the first and the last run in which the test was present.
+func (t testTrend) First() time.Duration {
+	for _, d := range t.Durations {
+		if d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func (t testTrend) Last() time.Duration {
+	for i := len(t.Durations) - 1; i >= 0; i-- {
+		if t.Durations[i] > 0 {
+			return t.Durations[i]
+		}
+	}
+	return 0
+}
+
+func (t testTrend) diff() testDiff {
+	return testDiff{Test: t.Test, OldDuration: t.First(), NewDuration: t.Last()}
+}
+
+// testTrends returns trends of tests matching the pattern, or of top slowest top-level tests of the last run
+// if the pattern is empty. Trends are sorted by the change of the duration, the biggest slowdown first.
+func testTrends(runs []historyRun, pattern string, top int) []testTrend {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	selected := map[parse.TestName]bool{}
+	if pattern != "" {
+		for _, run := range runs {
+			for _, t := range run.Tests {
+				if matchesTestPattern(pattern, t.Name()) {
+					selected[t.Name()] = true
+				}
+			}
+		}
+	} else {
+		var latest []historyTest
+		for _, t := range runs[len(runs)-1].Tests {
+			if !strings.Contains(t.Test, "/") {
+				latest = append(latest, t)
+			}
+		}
+		sort.Slice(latest, func(i, j int) bool {
+			if latest[i].Duration != latest[j].Duration {
+				return latest[i].Duration > latest[j].Duration
+			}
+			return latest[i].Name().String() < latest[j].Name().String()
+		})
+		for i := 0; i < len(latest) && i < top; i++ {
+			selected[latest[i].Name()] = true
+		}
+	}
+
+	trends := map[parse.TestName]*testTrend{}
+	for tn := range selected {
+		trends[tn] = &testTrend{Test: tn, Durations: make([]time.Duration, len(runs))}
+	}
+	for i, run := range runs {
+		for _, t := range run.Tests {
+			if trend, ok := trends[t.Name()]; ok {
+				trend.Durations[i] = t.Duration
+			}
+		}
+	}
+
+	result := make([]testTrend, 0, len(trends))
+	for _, trend := range trends {
+		result = append(result, *trend)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ci, cj := result[i].diff().Change(), result[j].diff().Change()
+		if ci != cj {
+			return ci > cj
+		}
+		return result[i].Test.String() < result[j].Test.String()
+	})
+
+	return result
+}
+
+func runLabel(run historyRun) string {
+	label := run.Time.Format(time.DateTime)
+	if run.Commit != "" {
+		label += " " + run.Commit[:min(len(run.Commit), 8)]
+	}
+	if run.Branch != "" {
+		label += " (" + run.Branch + ")"
+	}
+
+	return label
+}
+
+func writeHistoryReport(w io.Writer, runs []historyRun, trends []testTrend) {
+	if len(runs) == 0 {
+		_, _ = fmt.Fprintln(w, "No runs in the history.")
+		return
+	}
+
+	first, last := runs[0], runs[len(runs)-1]
+	total := testDiff{OldDuration: first.Duration, NewDuration: last.Duration}
+
+	_, _ = fmt.Fprintf(w, "Runs: %d, from %s to %s\n", len(runs), runLabel(first), runLabel(last))
+	_, _ = fmt.Fprintf(
+		w,
+		"Total: %s → %s (%s)\n",
+		first.Duration.Round(time.Millisecond),
+		last.Duration.Round(time.Millisecond),
+		formatChange(total),
+	)
+
+	if len(trends) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nTests (first → last run in which they were present):\n")
+	for _, trend := range trends {
+		d := trend.diff()
+		_, _ = fmt.Fprintf(
+			w,
+			"  %-70s %10s → %-10s (%s)\n",
+			trend.Test,
+			d.OldDuration.Round(time.Millisecond),
+			d.NewDuration.Round(time.Millisecond),
+			formatChange(d),
+		)
+	}
+}
+
+// historyCharts returns line charts of the total duration and durations of tests over runs.
+func historyCharts(runs []historyRun, trends []testTrend) (total []map[string]any, tests []map[string]any) {
+	x := make([]string, 0, len(runs))
+	labels := make([]string, 0, len(runs))
+	durations := make([]float64, 0, len(runs))
+	for _, run := range runs {
+		x = append(x, run.Time.Format(time.RFC3339))
+		labels = append(labels, runLabel(run))
+		durations = append(durations, run.Duration.Seconds())
+	}
+
+	total = []map[string]any{{
+		"type":      "scatter",
+		"mode":      "lines+markers",
+		"name":      "total",
+		"x":         x,
+		"y":         durations,
+		"text":      labels,
+		"hoverinfo": "text+y",
+	}}
+
+	for _, trend := range trends {
+		// tests missing in a run are presented as gaps
+		y := make([]*float64, 0, len(trend.Durations))
+		for _, d := range trend.Durations {
+			if d == 0 {
+				y = append(y, nil)
+				continue
+			}
+			seconds := d.Seconds()
+			y = append(y, &seconds)
+		}
+
+		tests = append(tests, map[string]any{
+			"type":      "scatter",
+			"mode":      "lines+markers",
+			"name":      trend.Test.String(),
+			"x":         x,
+			"y":         y,
+			"text":      labels,
+			"hoverinfo": "name+text+y",
+		})
+	}
+
+	return total, tests
+}
+
+func renderHistory(runs []historyRun, trends []testTrend, callOnLoad bool) (string, error) {
+	total, tests := historyCharts(runs, trends)
+
+	totalJSON, err := json.Marshal(total)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling total chart: %w", err)
+	}
+	testsJSON, err := json.Marshal(tests)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling tests chart: %w", err)
+	}
+
+	t, err := template.New("history").Option("missingkey=error").Parse(historyTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %w", err)
+	}
+
+	var out strings.Builder
+	err = t.Execute(&out, map[string]any{
+		"plotly":     template.JS(plotly),
+		"totalJSON":  template.JS(totalJSON),
+		"testsJSON":  template.JS(testsJSON),
+		"runs":       len(runs),
+		"callOnLoad": callOnLoad,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error executing template: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+// runHistory implements "vgt history dir" and returns the exit code.
+func runHistory(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: vgt history [flags] dir")
+		flags.PrintDefaults()
+	}
+
+	var pattern, branch string
+	var top, last int
+	var textOnly bool
+
+	flags.BoolVar(&debug, "debug", false, "enable debug mode")
+	flags.BoolVar(&printHTML, "print-html", false, "print html to stdout instead of opening browser")
+	flags.BoolVar(&keepRunning, "keep-running", false, "keep browser running after page was opened")
+	flags.BoolVar(&textOnly, "text", false, "print only the text report, without opening browser")
+	flags.StringVar(&pattern, "test", "", "glob pattern of tests to present, matched against the test name and the full name including the package (default: the slowest tests)")
+	flags.IntVar(&top, "top", 10, "number of the slowest top-level tests of the last run to present, when -test is not set")
+	flags.IntVar(&last, "last", 50, "number of the latest runs to present (0 presents all runs)")
+	flags.StringVar(&branch, "branch", "", "present only runs from the branch")
+	_ = flags.Parse(args)
+
+	setupLogger()
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	runs, err := readHistory(flags.Arg(0))
+	if err != nil {
+		slog.Error("Error reading history", "err", err)
+		return 1
+	}
+
+	if branch != "" {
+		var filtered []historyRun
+		for _, run := range runs {
+			if run.Branch == branch {
+				filtered = append(filtered, run)
+			}
+		}
+		runs = filtered
+	}
+	if last > 0 && len(runs) > last {
+		runs = runs[len(runs)-last:]
+	}
+
+	trends := testTrends(runs, pattern, top)
+
+	if !printHTML {
+		writeHistoryReport(os.Stdout, runs, trends)
+	}
+	if textOnly || len(runs) == 0 {
+		return 0
+	}
+
+	if printHTML {
+		html, err := renderHistory(runs, trends, false)
+		if err != nil {
+			slog.Error("Error rendering html", "err", err)
+			return 1
+		}
+		_, _ = os.Stdout.Write([]byte(html))
+		return 0
+	}
+
+	done := make(chan struct{})
+	close(done)
+	servePage(ctx, func() (string, error) { return renderHistory(runs, trends, true) }, nil, done)
+
+	return 0
+}
+
+const historyTemplate = `
+<!DOCTYPE html>
+<meta charset="utf-8">
+<html>
+<head>
+	<title>Test duration history ({{ .runs }} runs)</title>
+</head>
+<body>
+	<div id="total"></div>
+	<div id="tests"></div>
+</body>
+
+<style>
+body {
+	font-family: sans-serif;
+	margin: 0;
+}
+</style>
+
+<script>
+	{{ .plotly }}
+</script>
+<script>
+	const TOTAL = {{ .totalJSON }};
+	const TESTS = {{ .testsJSON }};
+
+	Plotly.newPlot('total', TOTAL, {
+		title: {text: 'Total duration'},
+		height: 300,
+		showlegend: false,
+		yaxis: {ticksuffix: 's', rangemode: 'tozero'},
+	});
+
+	if (TESTS.length > 0) {
+		Plotly.newPlot('tests', TESTS, {
+			title: {text: 'Test durations'},
+			height: 600,
+			yaxis: {ticksuffix: 's', rangemode: 'tozero'},
+			legend: {orientation: 'h'},
+		});
+	}
+</script>
+
+{{ if .callOnLoad }}
+<script>
+	// Send a request to /loaded when the page finishes loading
+	window.onload = function() {
+		fetch('/loaded');
+	}
+</script>
+{{ end }}
+`
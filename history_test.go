@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:15.000000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:15.000000+02:00","Action":"fail","Package":"example.com/pkg"}
+`
+	run := newHistoryRun(mustParse(t, input), "abc", "main")
+	require.Equal(t, 3*time.Second, run.Duration)
+	require.Len(t, run.Tests, 3)
+	require.True(t, run.Tests[2].Failed)
+
+	older := run
+	older.Time = run.Time.Add(-24 * time.Hour)
+	older.Tests = []historyTest{
+		{Package: "example.com/pkg", Test: "TestA", Duration: 2 * time.Second},
+		{Package: "example.com/pkg", Test: "TestB", Duration: time.Second},
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, appendHistory(dir, run))
+	require.NoError(t, appendHistory(dir, older))
+
+	runs, err := readHistory(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, "abc", runs[0].Commit)
+	require.True(t, runs[0].Time.Before(runs[1].Time), "runs are sorted by time")
+
+	trends := testTrends(runs, "", 10)
+	require.Len(t, trends, 2, "subtests are not presented by default")
+	require.Equal(t, "example.com/pkg/TestB", trends[0].Test.String(), "the biggest slowdown is first")
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, trends[0].Durations)
+
+	trends = testTrends(runs, "TestA/*", 10)
+	require.Len(t, trends, 1)
+	require.Equal(t, []time.Duration{0, time.Second}, trends[0].Durations)
+
+	html, err := renderHistory(runs, trends, false)
+	require.NoError(t, err)
+	require.Contains(t, html, "example.com/pkg/TestA/sub")
+}
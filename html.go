@@ -9,6 +9,8 @@ import (
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/roblaszczak/vgt/parse"
 )
 
 type PlotlyChart struct {
@@ -22,16 +24,45 @@ type PlotlyChart struct {
 	Width        []float64 `json:"width"`
 	Marker       struct {
 		Color []string `json:"color"`
+		// Line outlines bars, it's used for highlighting the critical path.
+		Line *plotlyLine `json:"line,omitempty"`
 	} `json:"marker"`
 	Hoverinfo string `json:"hoverinfo"`
+	// Customdata contains keys of tests presented by bars, used for showing test details.
+	Customdata []string `json:"customdata"`
+}
+
+type plotlyLine struct {
+	Color []string  `json:"color"`
+	Width []float64 `json:"width"`
+}
+
+// Clone returns a copy of the chart which can be modified without affecting c.
+func (c PlotlyChart) Clone() PlotlyChart {
+	c.Y = slices.Clone(c.Y)
+	c.X = slices.Clone(c.X)
+	c.Base = slices.Clone(c.Base)
+	c.Text = slices.Clone(c.Text)
+	c.Width = slices.Clone(c.Width)
+	c.Marker.Color = slices.Clone(c.Marker.Color)
+	if c.Marker.Line != nil {
+		c.Marker.Line = &plotlyLine{
+			Color: slices.Clone(c.Marker.Line.Color),
+			Width: slices.Clone(c.Marker.Line.Width),
+		}
+	}
+	c.Customdata = slices.Clone(c.Customdata)
+
+	return c
 }
 
 func (c *PlotlyChart) Add(
-	label, y string,
+	label, y, customdata string,
 	start, duration time.Duration,
 	color string,
 ) {
 	c.Y = append(c.Y, y)
+	c.Customdata = append(c.Customdata, customdata)
 
 	c.X = append(c.X, duration.Round(time.Millisecond*10).Seconds())
 	c.Base = append(c.Base, start.Round(time.Millisecond*10).Seconds())
@@ -40,8 +71,123 @@ func (c *PlotlyChart) Add(
 	c.Marker.Color = append(c.Marker.Color, color)
 }
 
-func render(pr ParseResult, charts []PlotlyChart, callOnLoad bool) (string, error) {
-	settings := map[string]any{
+// testDetails are presented in the output panel after clicking on the test's bar.
+type testDetails struct {
+	Name   string `json:"name"`
+	Failed bool   `json:"failed"`
+	// Parent is the key of the closest ancestor presented in the chart.
+	Parent   string        `json:"parent"`
+	Subtests int           `json:"subtests"`
+	Summary  string        `json:"summary"`
+	Attempts []testAttempt `json:"attempts"`
+}
+
+type testAttempt struct {
+	Attempt int      `json:"attempt"`
+	Failed  bool     `json:"failed"`
+	Output  []string `json:"output"`
+}
+
+func testDetailsByKey(pr parse.ParseResult) map[string]testDetails {
+	details := make(map[string]testDetails, len(pr.TestRuns))
+
+	for tn, runs := range pr.TestRuns {
+		d := testDetails{
+			Name: tn.String(),
+		}
+		if len(runs) > 1 {
+			d.Summary = pr.TestRuns.DurationStats(tn).String()
+		}
+
+		for _, run := range runs {
+			if run.HasDurationDiscrepancy() {
+				d.Summary = strings.TrimSpace(fmt.Sprintf(
+					"%s Attempt #%d: go test reported %s, but timestamps show %s.",
+					d.Summary,
+					run.Attempt,
+					run.Elapsed.Round(time.Millisecond),
+					run.TimestampDuration().Round(time.Millisecond),
+				))
+			}
+
+			if run.Status == parse.TestStatusSkipped && run.SkipReason != "" {
+				d.Summary = strings.TrimSpace(fmt.Sprintf("%s Skipped: %s", d.Summary, run.SkipReason))
+			}
+
+			d.Attempts = append(d.Attempts, testAttempt{
+				Attempt: run.Attempt,
+				Failed:  run.Status == parse.TestStatusFailed,
+				Output:  executionOutput(run),
+			})
+		}
+
+		d.Failed = pr.TestFailed(tn)
+		details[tn.String()] = d
+	}
+
+	for pkg, execution := range pr.Packages {
+		summary := fmt.Sprintf("Setup took %s.", execution.SetupDuration().Round(time.Millisecond))
+		output := withOmittedLines(execution.Output, execution.OmittedOutputLines)
+
+		if execution.BuildFailed {
+			summary = "Build failed."
+			output = append(
+				slices.Clone(withOmittedLines(execution.BuildOutput, execution.OmittedBuildOutputLines)),
+				output...,
+			)
+		}
+
+		details[packageDetailsKey(pkg)] = testDetails{
+			Name:    pkg,
+			Failed:  execution.Status == parse.TestStatusFailed,
+			Summary: summary,
+			Attempts: []testAttempt{
+				{Attempt: 1, Failed: execution.Status == parse.TestStatusFailed, Output: output},
+			},
+		}
+	}
+
+	var walk func(parent string, nodes []*parse.TestNode)
+	walk = func(parent string, nodes []*parse.TestNode) {
+		for _, node := range nodes {
+			d := details[node.Test.String()]
+			d.Parent = parent
+			d.Subtests = node.Descendants()
+			details[node.Test.String()] = d
+
+			walk(node.Test.String(), node.Children)
+		}
+	}
+
+	for _, root := range pr.TestTree() {
+		parent := ""
+		if pkg, ok := details[packageDetailsKey(root.Test.Package)]; ok {
+			parent = packageDetailsKey(root.Test.Package)
+			pkg.Subtests += 1 + root.Descendants()
+			details[parent] = pkg
+		}
+
+		walk(parent, []*parse.TestNode{root})
+	}
+
+	return details
+}
+
+// executionOutput returns the kept output of the test execution, marking omitted lines.
+func executionOutput(run parse.TestExecution) []string {
+	return withOmittedLines(run.Output, run.OmittedOutputLines)
+}
+
+func withOmittedLines(lines []string, omitted int) []string {
+	if omitted == 0 {
+		return lines
+	}
+
+	return append([]string{fmt.Sprintf("... %d earlier lines omitted", omitted)}, lines...)
+}
+
+func chartSettings() map[string]any {
+	return map[string]any{
 		"showlegend": false,
 		"yaxis": map[string]any{
 			"visible": false,
@@ -49,7 +195,32 @@ func render(pr ParseResult, charts []PlotlyChart, callOnLoad bool) (string, erro
 		"xaxis": map[string]any{
 			"ticksuffix": "s",
 		},
+		// keeps zoom when the chart is redrawn
+		"uirevision": "vgt",
+	}
+}
+
+// render renders the timeline, parallelismOpts are used by the parallelism analysis.
+// With watch, the page reloads when tests are re-run.
+func render(pr parse.ParseResult, charts []PlotlyChart, parallelismOpts parallelismOptions, callOnLoad, watch bool) (string, error) {
+	settings := chartSettings()
+
+	// charts can be rendered multiple times (for example, on every refresh of the live page)
+	// and concurrently, so they are not modified
+	cloned := make([]PlotlyChart, 0, len(charts))
+	for _, chart := range charts {
+		cloned = append(cloned, chart.Clone())
+	}
+	charts = cloned
+
+	// merged runs overlap, so they don't have a single critical path or parallelism
+	singleRun := pr.MergedRuns <= 1
+
+	var path []criticalPathStep
+	if singleRun {
+		path = criticalPath(pr)
 	}
+	highlightCriticalPath(charts, path)
 
 	slices.Reverse(charts)
 
@@ -63,14 +234,118 @@ func render(pr ParseResult, charts []PlotlyChart, callOnLoad bool) (string, erro
 		return "", fmt.Errorf("error marshalling settings: %w", err)
 	}
 
+	var parallelism parallelismAnalysis
+	concurrencyJSON := []byte("null")
+	if singleRun {
+		parallelism = analyzeParallelism(pr, parallelismOpts.Slots, parallelismOpts.Parallel)
+
+		concurrencyJSON, err = json.Marshal(concurrencyChart(parallelism))
+		if err != nil {
+			return "", fmt.Errorf("error marshalling concurrency chart: %w", err)
+		}
+	}
+
+	details := testDetailsByKey(pr)
+	for _, p := range parallelism.Packages {
+		if d, ok := details[packageDetailsKey(p.Package)]; ok {
+			d.Summary += " " + p.Summary()
+			details[packageDetailsKey(p.Package)] = d
+		}
+	}
+
+	var suggestions []parallelSuggestion
+	if singleRun && parallelismOpts.Parallel > 0 {
+		suggestions = suggestParallelTests(pr, parallelismOpts.Parallel)
+	}
+	for _, s := range suggestions {
+		if d, ok := details[s.Test.String()]; ok {
+			d.Summary = strings.TrimSpace(fmt.Sprintf(
+				"%s The test doesn't call t.Parallel, calling it could save about %s of the package's time.",
+				d.Summary,
+				s.Saving.Round(time.Millisecond),
+			))
+			details[s.Test.String()] = d
+		}
+	}
+
+	addCriticalPathDetails(details, pr, path)
+	addFlakyDetails(details, findFlakyTests(pr))
+
+	testsJSON, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling test details: %w", err)
+	}
+
 	slog.Debug("Generated HTML with charts", "charts", string(chartsJSON))
 
-	html := `
+	duration := pr.End.Sub(pr.Start)
+
+	return executeTemplate(map[string]any{
+		"plotly":          template.JS(plotly),
+		"chartsJSON":      template.JS(chartsJSON),
+		"settingsJSON":    template.JS(settingsJSON),
+		"testsJSON":       template.JS(testsJSON),
+		"concurrencyJSON": template.JS(concurrencyJSON),
+		"parallelism":     parallelism.Summary(),
+		"callOnLoad":      callOnLoad,
+		"live":            false,
+		"watch":           watch,
+		"passed":          pr.Counts.Passed,
+		"failed":          pr.Counts.Failed,
+		"skipped":         pr.Counts.Skipped,
+		"buildFailed":     pr.BuildFailures(),
+		"duration":        duration.Round(time.Millisecond).String(),
+	})
+}
+
+// renderLive renders a page which builds the chart from updates streamed from /events
+// and reloads itself when tests are finished.
+func renderLive(watch bool) (string, error) {
+	settingsJSON, err := json.MarshalIndent(chartSettings(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshalling settings: %w", err)
+	}
+
+	return executeTemplate(map[string]any{
+		"plotly":          template.JS(plotly),
+		"chartsJSON":      template.JS("[]"),
+		"settingsJSON":    template.JS(settingsJSON),
+		"testsJSON":       template.JS("{}"),
+		"concurrencyJSON": template.JS("null"),
+		"parallelism":     "",
+		"callOnLoad":      false,
+		"live":            true,
+		"watch":           watch,
+		"passed":          0,
+		"failed":          0,
+		"skipped":         0,
+		"buildFailed":     0,
+		"duration":        "running",
+	})
+}
+
+func executeTemplate(data map[string]any) (string, error) {
+	t, err := template.New("template").Parse(htmlTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %w", err)
+	}
+
+	t = t.Option("missingkey=error")
+
+	buf := new(strings.Builder)
+	if err := t.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("error executing template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+const htmlTemplate = `
 <!DOCTYPE html>
 <meta charset="utf-8">
 <html>
 <head>
-	<title>Test Results ({{.duration}} {{.passed}} passed, {{.failed}} failed)</title>
+	<title>Test Results ({{.duration}} {{.passed}} passed, {{.failed}} failed, {{.skipped}} skipped{{ if .buildFailed }}, {{.buildFailed}} build failed{{ end }})</title>
 </head>
 <body>
 	<div id="popover" class="popover">
@@ -79,7 +354,23 @@ func render(pr ParseResult, charts []PlotlyChart, callOnLoad bool) (string, erro
 		<span class="close-btn" onclick="closePopover()">&times;</span>
         <p>You can zoom chart with controls or by clicking and selecting area to zoom.</p>
     </div>
+	<div id="concurrency"></div>
 	<div id="chart"></div>
+	<div id="toolbar" class="toolbar">
+		<span id="collapse-buttons">
+			<button onclick="collapseAll(true)">Collapse subtests</button>
+			<button onclick="collapseAll(false)">Expand subtests</button>
+		</span>
+		<button id="critical-path-button" onclick="showOutput('critical-path')">Critical path</button>
+		<button id="flaky-tests-button" onclick="showOutput('flaky-tests')"></button>
+	</div>
+	<div id="output-panel" class="output-panel">
+		<span class="close-btn" onclick="closeOutput()">&times;</span>
+		<h3 id="output-title"></h3>
+		<p id="output-summary"></p>
+		<button id="output-collapse" onclick="toggleCollapsed(CURRENT_TEST)"></button>
+		<pre id="output-log"></pre>
+	</div>
 </body>
 
 <style>
@@ -91,12 +382,15 @@ body {
     width: 100%;
     min-height: 100vh;
     max-height: 100%;
-    position: absolute;
-    top: 0;
-    left: 0;
     margin: 0 auto;
 }
 
+#concurrency {
+    width: 100%;
+    height: 200px;
+    display: none;
+}
+
 .popover {
     font-family: "Open Sans", verdana, arial, sans-serif;
     position: fixed;
@@ -134,6 +428,45 @@ body {
 .close-btn {
 	cursor: pointer;
 	float: right;
+}
+
+.toolbar {
+    position: fixed;
+    top: 10px;
+    left: 10px;
+    display: none;
+    z-index: 999;
+}
+
+.output-panel {
+    font-family: "Open Sans", verdana, arial, sans-serif;
+    position: fixed;
+    top: 0;
+    right: 0;
+    width: 45%;
+    height: 100%;
+    overflow: auto;
+    background-color: #fafafa;
+    border-left: 4px solid #999;
+    box-shadow: 0 0 10px rgba(0,0,0,0.2);
+    padding: 10px 20px;
+    box-sizing: border-box;
+    display: none;
+    z-index: 1001;
+}
+.output-panel.failed {
+    border-left-color: rgb(255, 0, 0);
+}
+.output-panel.failed h3 {
+    color: rgb(200, 0, 0);
+}
+.output-panel pre {
+    font-size: 12px;
+    white-space: pre-wrap;
+}
+.output-panel .highlight {
+    background-color: rgba(255, 0, 0, 0.15);
+}
 </style>
 
 <script>
@@ -141,14 +474,315 @@ body {
 </script>
 
 <script>
+	CHARTS = {{ .chartsJSON }};
+	SETTINGS = {{ .settingsJSON }};
+	TESTS = {{ .testsJSON }};
+
+	// keys of tests with hidden subtests
+	COLLAPSED = new Set();
+	CURRENT_TEST = null;
+
 	CHART = document.getElementById('chart');
-	Plotly.newPlot( 
-		CHART, 
-		{{ .chartsJSON }}, 
-		{{ .settingsJSON }}
-    );
+	Plotly.newPlot(CHART, CHARTS, SETTINGS);
+
+	CONCURRENCY = {{ .concurrencyJSON }};
+	if (CONCURRENCY) {
+		const concurrencyChart = document.getElementById('concurrency');
+		concurrencyChart.style.display = 'block';
+
+		Plotly.newPlot(concurrencyChart, CONCURRENCY, {
+			title: {text: {{ .parallelism }}, font: {size: 14}},
+			showlegend: false,
+			margin: {t: 40, b: 30},
+			xaxis: {ticksuffix: 's'},
+			yaxis: {title: {text: 'running tests'}, rangemode: 'tozero'},
+		});
+
+		// keeps the same range of time as the timeline
+		CHART.on('plotly_relayout', function (e) {
+			if (e['xaxis.range[0]'] !== undefined) {
+				Plotly.relayout(concurrencyChart, {'xaxis.range': [e['xaxis.range[0]'], e['xaxis.range[1]']]});
+			} else if (e['xaxis.autorange']) {
+				Plotly.relayout(concurrencyChart, {'xaxis.autorange': true});
+			}
+		});
+	}
+
+	const hasSubtests = Object.values(TESTS).some(test => test.subtests > 0);
+	const hasCriticalPath = 'critical-path' in TESTS;
+	const hasFlakyTests = 'flaky-tests' in TESTS;
+	if (hasSubtests || hasCriticalPath || hasFlakyTests) {
+		document.getElementById('toolbar').style.display = 'block';
+		document.getElementById('collapse-buttons').style.display = hasSubtests ? 'inline' : 'none';
+		document.getElementById('critical-path-button').style.display = hasCriticalPath ? 'inline-block' : 'none';
+		document.getElementById('flaky-tests-button').style.display = hasFlakyTests ? 'inline-block' : 'none';
+		if (hasFlakyTests) {
+			document.getElementById('flaky-tests-button').textContent = TESTS['flaky-tests'].name;
+		}
+	}
+
+	function isHidden(key) {
+		for (let parent = TESTS[key] && TESTS[key].parent; parent; parent = TESTS[parent] && TESTS[parent].parent) {
+			if (COLLAPSED.has(parent)) {
+				return true;
+			}
+		}
+		return false;
+	}
+
+	function redrawCollapsed() {
+		const visible = [];
+		for (const chart of CHARTS) {
+			const key = chart.customdata && chart.customdata[0];
+			if (isHidden(key)) {
+				continue;
+			}
+			if (COLLAPSED.has(key)) {
+				const suffix = ' [+' + TESTS[key].subtests + ' subtests]';
+				visible.push(Object.assign({}, chart, {text: chart.text.map(text => text + suffix)}));
+			} else {
+				visible.push(chart);
+			}
+		}
+		Plotly.react(CHART, visible, SETTINGS);
+	}
+
+	function toggleCollapsed(key) {
+		if (COLLAPSED.has(key)) {
+			COLLAPSED.delete(key);
+		} else {
+			COLLAPSED.add(key);
+		}
+		redrawCollapsed();
+		updateCollapseButton();
+	}
+
+	function collapseAll(collapse) {
+		COLLAPSED.clear();
+		if (collapse) {
+			for (const [key, test] of Object.entries(TESTS)) {
+				if (test.subtests > 0) {
+					COLLAPSED.add(key);
+				}
+			}
+		}
+		redrawCollapsed();
+		updateCollapseButton();
+	}
+
+	function updateCollapseButton() {
+		const button = document.getElementById('output-collapse');
+		const test = TESTS[CURRENT_TEST];
+		if (!test || test.subtests === 0) {
+			button.style.display = 'none';
+			return;
+		}
+		button.style.display = 'inline-block';
+		button.textContent = (COLLAPSED.has(CURRENT_TEST) ? 'Expand ' : 'Collapse ') + test.subtests + ' subtests';
+	}
+
+	CHART.on('plotly_click', function (data) {
+		if (data.points.length === 0 || !data.points[0].customdata) {
+			return;
+		}
+		showOutput(data.points[0].customdata);
+	});
+
+	function showOutput(key) {
+		const test = TESTS[key];
+		if (!test) {
+			return;
+		}
+
+		CURRENT_TEST = key;
+		updateCollapseButton();
+
+		const panel = document.getElementById('output-panel');
+		panel.classList.toggle('failed', test.failed);
+		document.getElementById('output-title').textContent = test.name + (test.failed ? ' (failed)' : '');
+
+		document.getElementById('output-summary').textContent = test.summary;
+
+		const log = document.getElementById('output-log');
+		log.replaceChildren();
+		for (const attempt of test.attempts) {
+			if (test.attempts.length > 1) {
+				const header = document.createElement('h4');
+				header.textContent = 'Attempt #' + attempt.attempt + (attempt.failed ? ' (failed)' : '');
+				log.appendChild(header);
+			}
+
+			for (const line of attempt.output || []) {
+				const el = document.createElement('div');
+				el.textContent = line;
+				// in failed tests, highlight failures and lines logged with t.Log/t.Error
+				if (attempt.failed && /^(\s*--- FAIL|\s*\S+\.go:\d+:)/.test(line)) {
+					el.classList.add('highlight');
+				}
+				log.appendChild(el);
+			}
+		}
+		if (log.childElementCount === 0) {
+			log.textContent = 'No output.';
+		}
+
+		panel.style.display = 'block';
+	}
+
+	function closeOutput() {
+		document.getElementById('output-panel').style.display = 'none';
+	}
+</script>
+
+{{ if .live }}
+<script>
+	// Chart is built from updates streamed by the server while tests are running.
+	const LIVE_SETTINGS = {{ .settingsJSON }};
+	const liveExecutions = {};
+	let liveLatestTime = null;
+	let liveLatestReceived = Date.now();
+
+	function liveParseTime(t) {
+		const ms = Date.parse(t);
+		// zero time.Time means that execution is still in progress
+		return ms > 0 ? ms : null;
+	}
+
+	function liveNow() {
+		return liveLatestTime + (Date.now() - liveLatestReceived);
+	}
+
+	function liveColor(execution, duration, maxDuration) {
+		if (execution.Status === 'failed') {
+			return 'rgba(255, 0, 0, 100)';
+		}
+		if (execution.Status === 'skipped') {
+			return 'rgba(180, 180, 180, 100)';
+		}
+		const value = maxDuration > 0 ? Math.min(1, duration / maxDuration) : 0;
+		return 'rgba(' + Math.round(60 * value) + ', ' + Math.round(180 * (1 - value)) + ', ' + Math.round(200 + 30 * value) + ', 100)';
+	}
+
+	function liveRedraw() {
+		if (liveLatestTime === null) {
+			return;
+		}
+
+		const tests = {};
+		let start = null;
+
+		for (const execution of Object.values(liveExecutions)) {
+			const s = liveParseTime(execution.Start);
+			if (s === null) {
+				continue;
+			}
+			if (start === null || s < start) {
+				start = s;
+			}
+
+			const key = execution.Test.Package + '/' + execution.Test.TestName;
+			if (!tests[key]) {
+				tests[key] = {test: execution.Test, start: s, attempts: []};
+			}
+			tests[key].attempts.push(execution);
+			tests[key].start = Math.min(tests[key].start, s);
+		}
+
+		const now = liveNow();
+		let maxDuration = 0;
+		for (const t of Object.values(tests)) {
+			for (const execution of t.attempts) {
+				if (liveParseTime(execution.End) !== null) {
+					maxDuration = Math.max(maxDuration, liveRunDuration(execution, now));
+				}
+			}
+		}
+
+		const traces = Object.values(tests).sort((a, b) => a.start - b.start).map(t => {
+			const packageParts = t.test.Package.split('/');
+			const name = packageParts[packageParts.length - 1] + '.' + t.test.TestName;
+
+			const trace = {
+				type: 'bar', orientation: 'h', hoverinfo: 'text', textposition: 'inside',
+				y: [], x: [], base: [], text: [], width: [], marker: {color: []},
+			};
+			const add = (label, from, to, color) => {
+				trace.y.push(name);
+				trace.x.push((to - from) / 1000);
+				trace.base.push((from - start) / 1000);
+				trace.text.push(label + ' (' + ((to - from) / 1000).toFixed(2) + 's)');
+				trace.width.push(0.9);
+				trace.marker.color.push(color);
+			};
+
+			for (const execution of t.attempts) {
+				const finished = liveParseTime(execution.End) !== null;
+
+				for (const segment of execution.Segments || []) {
+					const from = liveParseTime(segment.Start);
+					const to = liveParseTime(segment.End);
+
+					if (segment.Kind === 'pause') {
+						add(name + ' PAUSE', from, to === null ? now : to, 'rgba(108,122,137,1)');
+					} else if (finished) {
+						add(name + ' RUN', from, to, liveColor(execution, liveRunDuration(execution, now), maxDuration));
+					} else {
+						add(name + ' RUNNING', from, to === null ? now : to, 'rgba(255, 165, 0, 100)');
+					}
+				}
+			}
+
+			return trace;
+		});
+		traces.reverse();
+
+		Plotly.react(CHART, traces, LIVE_SETTINGS);
+	}
+
+	function liveRunDuration(execution, now) {
+		let duration = 0;
+		for (const segment of execution.Segments || []) {
+			if (segment.Kind === 'run') {
+				const to = liveParseTime(segment.End);
+				duration += (to === null ? now : to) - liveParseTime(segment.Start);
+			}
+		}
+		return duration;
+	}
+
+	const liveSource = new EventSource('/events');
+	liveSource.addEventListener('update', function (e) {
+		const execution = JSON.parse(e.data).Execution;
+		liveExecutions[execution.Test.Package + '/' + execution.Test.TestName + '#' + execution.Attempt] = execution;
+
+		const times = [liveParseTime(execution.Start), liveParseTime(execution.End)];
+		for (const segment of execution.Segments || []) {
+			times.push(liveParseTime(segment.Start), liveParseTime(segment.End));
+		}
+		for (const t of times) {
+			if (t !== null && (liveLatestTime === null || t > liveLatestTime)) {
+				liveLatestTime = t;
+				liveLatestReceived = Date.now();
+			}
+		}
+	});
+	liveSource.addEventListener('done', function () {
+		liveSource.close();
+		window.location.reload();
+	});
+
+	setInterval(liveRedraw, 500);
+</script>
+{{ end }}
 
+{{ if .watch }}
+<script>
+	// tests are re-run when files are changed, the page presents the latest run
+	new EventSource('/watch').addEventListener('run', function () {
+		location.reload();
+	});
 </script>
+{{ end }}
 
 {{ if .callOnLoad }}
 <script>
@@ -195,42 +829,6 @@ body {
 </script>
 </html>
 `
-	t, err := template.New("template").Parse(html)
-	if err != nil {
-		return "", fmt.Errorf("error parsing template: %w", err)
-	}
-
-	t = t.Option("missingkey=error")
-
-	passed := 0
-	failed := 0
-
-	for _, execution := range pr.TestRuns {
-		if execution.Passed {
-			passed++
-		} else {
-			failed++
-		}
-	}
-
-	duration := pr.End.Sub(pr.Start)
-
-	buf := new(strings.Builder)
-	err = t.Execute(buf, map[string]any{
-		"plotly":       template.JS(plotly),
-		"chartsJSON":   template.JS(chartsJSON),
-		"settingsJSON": template.JS(settingsJSON),
-		"callOnLoad":   callOnLoad,
-		"passed":       passed,
-		"failed":       failed,
-		"duration":     duration.Round(time.Millisecond).String(),
-	})
-	if err != nil {
-		return "", fmt.Errorf("error executing template: %w", err)
-	}
-
-	return buf.String(), nil
-}
 
 func floatToColor(value float64) string {
 	value = math.Max(0, math.Min(1, value))
@@ -242,9 +840,12 @@ func floatToColor(value float64) string {
 	return fmt.Sprintf("rgba(%d, %d, %d, 100)", r, g, b)
 }
 
-func durationToRgb(d TestExecution, maxDuration time.Duration) string {
-	if !d.Passed {
-		return "rgba(255, 0, 0, 100)"
+func durationToRgb(d parse.TestExecution, maxDuration time.Duration) string {
+	switch d.Status {
+	case parse.TestStatusFailed:
+		return failedColor
+	case parse.TestStatusSkipped:
+		return skippedColor
 	}
 
 	position := float64(d.Duration()) / float64(maxDuration)
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+var updateGolden = false
+
+func init() {
+	flag.BoolVar(&updateGolden, "update-golden", false, "update golden files")
+}
+
+func TestRender(t *testing.T) {
+	testOutput, err := os.ReadFile("testdata/test.json")
+	require.NoError(t, err)
+
+	parseResult, err := parse.Parse(bytes.NewBuffer(testOutput), parse.Options{})
+	require.NoError(t, err)
+
+	charts := generateCharts(parseResult)
+
+	html, err := render(parseResult, charts, parallelismOptions{}, false, false)
+	require.NoError(t, err)
+
+	if updateGolden {
+		err = os.WriteFile("testdata/golden.html", []byte(html), 0644)
+		require.NoError(t, err)
+	} else {
+		golden, err := os.ReadFile("testdata/golden.html")
+		require.NoError(t, err)
+
+		require.Equal(t, string(golden), html)
+	}
+}
+
+func TestRender_same_charts_twice(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg"}
+`
+	result := mustParse(t, input)
+
+	charts := generateCharts(result)
+	original := make([]PlotlyChart, 0, len(charts))
+	for _, chart := range charts {
+		original = append(original, chart.Clone())
+	}
+
+	first, err := render(result, charts, parallelismOptions{}, false, false)
+	require.NoError(t, err)
+
+	second, err := render(result, charts, parallelismOptions{}, false, false)
+	require.NoError(t, err)
+
+	require.Equal(t, first, second)
+	require.Equal(t, original, charts, "charts are rendered again on every refresh, so they should not be modified")
+}
+
+func mustParse(t *testing.T, input string) parse.ParseResult {
+	t.Helper()
+
+	result, err := parse.Parse(bytes.NewBufferString(input), parse.Options{})
+	require.NoError(t, err)
+
+	return result
+}
+
+func TestGenerateCharts_repeated_runs(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"skip","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+`
+
+	result := mustParse(t, input)
+
+	charts := generateCharts(result)
+	require.Len(t, charts, 1)
+	require.Len(t, charts[0].X, 3)
+	require.Equal(t, []string{skippedColor, failedColor}, charts[0].Marker.Color[1:])
+	require.Equal(t, "pkg.TestA (flaky)", charts[0].Y[0], "the test both passed and failed")
+}
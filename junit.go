@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemOut *junitOutput    `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	Classname string `xml:"classname,attr"`
+	Name      string `xml:"name,attr"`
+	Time      string `xml:"time,attr"`
+	// Properties mark attempts which failed, but the test passed when it was re-run.
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Error      *junitMessage   `xml:"error,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  *junitOutput    `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+type junitOutput struct {
+	Contents string `xml:",chardata"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func junitLines(lines []string) string {
+	return strings.Join(lines, "\n")
+}
+
+// renderJUnit converts results to JUnit XML, with a test suite per package.
+// Every attempt of a test is a separate test case, so results of -count=N runs are not lost.
+// Failed attempts of tests which passed when re-run are reported as flaky, not as failures.
+// The result should not be filtered with the duration cutoff, because all tests should be reported.
+func renderJUnit(pr parse.ParseResult) ([]byte, error) {
+	testsByPackage := map[string][]parse.TestName{}
+	for _, tn := range pr.TestNamesInTreeOrder() {
+		testsByPackage[tn.Package] = append(testsByPackage[tn.Package], tn)
+	}
+
+	suites := junitTestSuites{
+		Time: junitTime(pr.End.Sub(pr.Start)),
+	}
+
+	for _, pkg := range pr.PackagesOrderedByStart() {
+		execution, hasExecution := pr.Packages[pkg]
+
+		suite := junitTestSuite{
+			Name: pkg,
+		}
+		if hasExecution {
+			duration := execution.Duration()
+			if execution.Elapsed > 0 {
+				duration = execution.Elapsed
+			}
+			suite.Time = junitTime(duration)
+			if !execution.Start.IsZero() {
+				suite.Timestamp = execution.Start.Format(time.RFC3339)
+			}
+			if len(execution.Output) > 0 {
+				suite.SystemOut = &junitOutput{Contents: junitLines(withOmittedLines(execution.Output, execution.OmittedOutputLines))}
+			}
+		}
+
+		for _, tn := range testsByPackage[pkg] {
+			runs, _ := pr.TestRuns.ByTestName(tn)
+
+			for _, run := range runs {
+				testCase := junitTestCase{
+					Classname: pkg,
+					Name:      tn.TestName,
+					Time:      junitTime(run.Duration()),
+				}
+
+				switch {
+				case run.Status == parse.TestStatusFailed && pr.Reruns && !pr.TestFailed(tn):
+					// the test passed when it was re-run, so it's flaky, not failed
+					testCase.Properties = []junitProperty{{Name: "flaky", Value: "true"}}
+					testCase.SystemOut = &junitOutput{Contents: junitLines(executionOutput(run))}
+				case run.Status == parse.TestStatusFailed:
+					testCase.Failure = &junitMessage{Message: "Failed", Contents: junitLines(executionOutput(run))}
+					suite.Failures++
+				case run.Status == parse.TestStatusSkipped:
+					testCase.Skipped = &junitMessage{Message: run.SkipReason}
+					suite.Skipped++
+				case run.Status == parse.TestStatusRunning:
+					// the test binary crashed or was killed before the test finished
+					testCase.Error = &junitMessage{Message: "Test didn't finish", Contents: junitLines(executionOutput(run))}
+					suite.Errors++
+				default:
+					if len(run.Output) > 0 {
+						testCase.SystemOut = &junitOutput{Contents: junitLines(executionOutput(run))}
+					}
+				}
+
+				suite.TestCases = append(suite.TestCases, testCase)
+			}
+		}
+
+		// failures which are not attributed to any test would be lost otherwise
+		if hasExecution && execution.BuildFailed {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Classname: pkg,
+				Name:      "[build failed]",
+				Time:      junitTime(0),
+				Error: &junitMessage{
+					Message:  "Build failed",
+					Contents: junitLines(withOmittedLines(execution.BuildOutput, execution.OmittedBuildOutputLines)),
+				},
+			})
+			suite.Errors++
+		} else if hasExecution && execution.Status == parse.TestStatusFailed && suite.Failures+suite.Errors == 0 {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Classname: pkg,
+				Name:      "[package failed]",
+				Time:      junitTime(execution.SetupDuration()),
+				Error: &junitMessage{
+					Message:  "Package failed outside of tests",
+					Contents: junitLines(withOmittedLines(execution.Output, execution.OmittedOutputLines)),
+				},
+			})
+			suite.Errors++
+		}
+
+		suite.Tests = len(suite.TestCases)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	out, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling junit xml: %w", err)
+	}
+
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+func writeJUnitFile(pr parse.ParseResult, path string) error {
+	report, err := renderJUnit(pr)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, report, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+
+	return nil
+}
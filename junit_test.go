@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+func TestRenderJUnit(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestFast"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestFast","Elapsed":0}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestFailed"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestFailed","Output":"    a_test.go:10: boom\n"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestFailed","Elapsed":0.5}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestSkipped"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestSkipped","Output":"    a_test.go:20: needs docker\n"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestSkipped","Output":"--- SKIP: TestSkipped (0.00s)\n"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"skip","Package":"example.com/pkg","Test":"TestSkipped","Elapsed":0}
+{"Time":"2024-09-18T21:02:12.700000+02:00","Action":"fail","Package":"example.com/pkg","Elapsed":0.7}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./a_test.go:3:1: syntax error\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Time":"2024-09-18T21:02:12.700000+02:00","Action":"start","Package":"example.com/broken"}
+{"Time":"2024-09-18T21:02:12.700000+02:00","Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+`
+
+	pr, err := parse.Parse(bytes.NewBufferString(input), parse.Options{KeepAllExecutions: true})
+	require.NoError(t, err)
+
+	report, err := renderJUnit(pr)
+	require.NoError(t, err)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(report, &suites))
+
+	require.Equal(t, 4, suites.Tests)
+	require.Equal(t, 1, suites.Failures)
+	require.Equal(t, 1, suites.Errors)
+	require.Equal(t, 1, suites.Skipped)
+	require.Len(t, suites.Suites, 2)
+
+	pkg := suites.Suites[0]
+	require.Equal(t, "example.com/pkg", pkg.Name)
+	require.Equal(t, "0.700", pkg.Time)
+	require.Len(t, pkg.TestCases, 3)
+
+	// TestFailed and TestFast started at the same time, so they are ordered by name
+	require.Equal(t, "TestFailed", pkg.TestCases[0].Name)
+	require.Equal(t, "0.500", pkg.TestCases[0].Time)
+	require.NotNil(t, pkg.TestCases[0].Failure)
+	require.Contains(t, pkg.TestCases[0].Failure.Contents, "a_test.go:10: boom")
+
+	require.Equal(t, "TestFast", pkg.TestCases[1].Name, "tests below the duration cutoff should be reported")
+	require.Equal(t, "0.000", pkg.TestCases[1].Time)
+
+	require.NotNil(t, pkg.TestCases[2].Skipped)
+	require.Equal(t, "needs docker", pkg.TestCases[2].Skipped.Message)
+
+	broken := suites.Suites[1]
+	require.Len(t, broken.TestCases, 1)
+	require.NotNil(t, broken.TestCases[0].Error)
+	require.Contains(t, broken.TestCases[0].Error.Contents, "syntax error")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// liveTimeline keeps the latest state of test executions while tests are running
+// and broadcasts changes to connected browsers via Server-Sent Events.
+type liveTimeline struct {
+	lock sync.Mutex
+
+	executions  map[string]parse.ExecutionUpdate
+	subscribers map[*liveSubscriber]struct{}
+
+	result *parse.ParseResult
+	charts []PlotlyChart
+	done   chan struct{}
+}
+
+type liveSubscriber struct {
+	// pending updates are coalesced by key, so slow clients are not flooded with stale updates
+	pending map[string]parse.ExecutionUpdate
+	notify  chan struct{}
+}
+
+func newLiveTimeline() *liveTimeline {
+	return &liveTimeline{
+		executions:  map[string]parse.ExecutionUpdate{},
+		subscribers: map[*liveSubscriber]struct{}{},
+		done:        make(chan struct{}),
+	}
+}
+
+func liveUpdateKey(u parse.ExecutionUpdate) string {
+	return fmt.Sprintf("%s#%d", u.Execution.Test, u.Execution.Attempt)
+}
+
+// Update can be used as parse.Options.OnUpdate callback.
+func (l *liveTimeline) Update(u parse.ExecutionUpdate) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key := liveUpdateKey(u)
+	l.executions[key] = u
+
+	for s := range l.subscribers {
+		s.pending[key] = u
+		s.signal()
+	}
+}
+
+// Finish stores the final result and notifies all subscribers that there will be no more updates.
+func (l *liveTimeline) Finish(pr parse.ParseResult) {
+	l.FinishWithCharts(pr, generateCharts(pr))
+}
+
+// FinishWithCharts is like Finish, but presents the given charts instead of the result's timeline.
+func (l *liveTimeline) FinishWithCharts(pr parse.ParseResult, charts []PlotlyChart) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.result = &pr
+	l.charts = charts
+	close(l.done)
+}
+
+// Done is closed when the final result is available.
+func (l *liveTimeline) Done() <-chan struct{} {
+	return l.done
+}
+
+// Result returns the final result, or false if tests are still running.
+func (l *liveTimeline) Result() (parse.ParseResult, []PlotlyChart, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.result == nil {
+		return parse.ParseResult{}, nil, false
+	}
+
+	return *l.result, l.charts, true
+}
+
+func (l *liveTimeline) subscribe() *liveSubscriber {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	s := &liveSubscriber{
+		pending: make(map[string]parse.ExecutionUpdate, len(l.executions)),
+		notify:  make(chan struct{}, 1),
+	}
+	for key, u := range l.executions {
+		s.pending[key] = u
+	}
+	s.signal()
+
+	l.subscribers[s] = struct{}{}
+
+	return s
+}
+
+func (l *liveTimeline) unsubscribe(s *liveSubscriber) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.subscribers, s)
+}
+
+func (l *liveTimeline) takePending(s *liveSubscriber) []parse.ExecutionUpdate {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	updates := make([]parse.ExecutionUpdate, 0, len(s.pending))
+	for key, u := range s.pending {
+		updates = append(updates, u)
+		delete(s.pending, key)
+	}
+
+	return updates
+}
+
+func (s *liveSubscriber) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+		// subscriber was already notified
+	}
+}
+
+func (l *liveTimeline) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	s := l.subscribe()
+	defer l.unsubscribe(s)
+
+	for {
+		select {
+		case <-s.notify:
+			for _, u := range l.takePending(s) {
+				data, err := json.Marshal(u)
+				if err != nil {
+					slog.Error("Error marshalling live update", "err", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: update\ndata: %s\n\n", data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		case <-l.done:
+			_, _ = fmt.Fprint(w, "event: done\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
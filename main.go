@@ -1,23 +1,24 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	_ "embed"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
-	"os/exec"
 	"os/signal"
+	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/lmittmann/tint"
 	"github.com/muesli/cancelreader"
+
+	"github.com/roblaszczak/vgt/parse"
 )
 
 //go:embed node_modules/plotly.js-dist/plotly.js
@@ -30,17 +31,81 @@ var testDurationCutoff string
 var testDurationCutoffDuration time.Duration
 var printHTML bool
 var keepRunning bool
-var fromFile string
+var fromFiles stringsFlag
+var liveMode bool
+var outputFormat string
+var tuiMode bool
+var asciiMode bool
+var junitFile string
+var budgets durationBudgets
+var budgetFile string
+var gomaxprocs int
+var testParallelFlag int
+var parallelismReport bool
+var criticalPathReport bool
+var flakyReport bool
+var rerunFails int
+var watchMode bool
+var historyDir string
+
+const (
+	formatHTML        = "html"
+	formatChromeTrace = "chrome-trace"
+	formatSVG         = "svg"
+	formatJUnit       = "junit"
+)
+
+var outputFormats = []string{formatHTML, formatChromeTrace, formatSVG, formatJUnit}
 
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		exitCode := runDiff(ctx, os.Args[2:])
+		stop()
+		os.Exit(exitCode)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		exitCode := runHistory(ctx, os.Args[2:])
+		stop()
+		os.Exit(exitCode)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "shard" {
+		exitCode := runShard(os.Args[2:])
+		stop()
+		os.Exit(exitCode)
+	}
+
 	flag.BoolVar(&debug, "debug", false, "enable debug mode")
 	flag.BoolVar(&dontPassOutput, "dont-pass-output", false, "don't print output received to stdin")
 	flag.BoolVar(&keepRunning, "keep-running", false, "keep browser running after page was opened")
 	flag.BoolVar(&printHTML, "print-html", false, "print html to stdout instead of opening browser")
-	flag.StringVar(&fromFile, "from-file", "", "read input from file instead of stdin")
+	flag.Var(&fromFiles, "from-file", "read input from file instead of stdin, can be repeated to merge executions from multiple runs (started at the same time on the timeline)")
+	flag.BoolVar(&liveMode, "live", false, "open browser immediately and update the chart while tests are running")
+	flag.BoolVar(&watchMode, "watch", false, "re-run tests of affected packages when files are changed, and present the latest run in the browser")
+	flag.BoolVar(&tuiMode, "tui", false, "present the timeline in the terminal, with scrolling, zooming and filtering")
+	flag.BoolVar(&asciiMode, "ascii", false, "print the timeline to stdout, scaled to the terminal width")
+	flag.DurationVar(&budgets.Test, "max-test-duration", 0, "fail if any test takes longer (0 disables the limit)")
+	flag.DurationVar(&budgets.Package, "max-package-duration", 0, "fail if any package takes longer (0 disables the limit)")
+	flag.DurationVar(&budgets.Total, "max-total-duration", 0, "fail if the whole run takes longer (0 disables the limit)")
+	flag.StringVar(&budgetFile, "budget-file", "", "file with duration budgets of tests matching glob patterns, one \"pattern duration\" per line")
+	flag.IntVar(&gomaxprocs, "gomaxprocs", runtime.GOMAXPROCS(0), "GOMAXPROCS of the machine running tests, used by the parallelism analysis")
+	flag.IntVar(&testParallelFlag, "test-parallel", 0, "-parallel flag of go test, used by the parallelism analysis (default: detected from go test arguments or -gomaxprocs)")
+	flag.BoolVar(&parallelismReport, "parallelism", false, "print the parallelism analysis and tests which could call t.Parallel to stderr")
+	flag.BoolVar(&criticalPathReport, "critical-path", false, "print the critical path of the run, executions which determined its total duration, to stderr")
+	flag.IntVar(&rerunFails, "rerun-fails", 0, "re-run failed tests up to N times, tests which passed when re-run are flaky and don't fail the run")
+	flag.BoolVar(&flakyReport, "flaky", false, "print tests which both passed and failed to stderr")
+	flag.StringVar(&historyDir, "history", "", "append a summary of the run to the history in the directory, presented by vgt history")
+	flag.StringVar(&junitFile, "junit-file", "", "write JUnit XML report to the file, in addition to the chosen output")
+	flag.StringVar(
+		&outputFormat,
+		"format",
+		formatHTML,
+		fmt.Sprintf("output format, one of: %s (formats other than html are printed to stdout)", strings.Join(outputFormats, ", ")),
+	)
 
 	flag.StringVar(
 		&testDurationCutoff,
@@ -50,90 +115,264 @@ func main() {
 	)
 	flag.Parse()
 
-	logLevel := slog.LevelInfo
-	if debug {
-		logLevel = slog.LevelDebug
-	}
-
 	var err error
 	testDurationCutoffDuration, err = time.ParseDuration(testDurationCutoff)
 	if err != nil {
 		panic(err)
 	}
 
-	slog.SetDefault(slog.New(
-		tint.NewHandler(os.Stderr, &tint.Options{
-			Level:      logLevel,
-			TimeFormat: time.Kitchen,
-		}),
-	))
+	setupLogger()
+
+	if !slices.Contains(outputFormats, outputFormat) {
+		slog.Error("Unknown output format", "format", outputFormat, "available", outputFormats)
+		return
+	}
+
+	if liveMode && printsToStdout() {
+		slog.Error("Can't use -live with -print-html, -ascii or -format other than html")
+		return
+	}
+
+	if tuiMode && (liveMode || printsToStdout()) {
+		slog.Error("Can't use -tui with -live, -print-html, -ascii or -format other than html")
+		return
+	}
+
+	if watchMode && (liveMode || tuiMode || printsToStdout() || len(fromFiles) > 0) {
+		slog.Error("Can't use -watch with -live, -tui, -from-file, -print-html, -ascii or -format other than html")
+		return
+	}
+
+	if len(fromFiles) > 1 && (liveMode || parallelismReport || criticalPathReport || historyDir != "" || budgets.Total > 0) {
+		slog.Error("Can't use -live, -parallelism, -critical-path, -history or -max-total-duration with multiple -from-file inputs, they describe a single run")
+		return
+	}
+
+	if budgetFile != "" {
+		budgets.Tests, err = readBudgetFile(budgetFile)
+		if err != nil {
+			slog.Error("Invalid budget file", "err", err)
+			return
+		}
+	}
 
-	r, cleanup, exitCode, done := newReader(ctx)
+	if watchMode {
+		exitCode := runWatch(ctx, flag.Args())
+		stop()
+		os.Exit(exitCode)
+	}
+
+	readers, finish, done := newReaders(ctx)
 	if !done {
 		return
 	}
-	defer cleanup()
 
-	scanner := bufio.NewScanner(r)
+	timeline := newLiveTimeline()
+
+	var onUpdate func(parse.ExecutionUpdate)
+	serverDone := make(chan struct{})
 
-	result := Parse(scanner)
+	if liveMode {
+		onUpdate = timeline.Update
+
+		go func() {
+			defer close(serverDone)
+			serveHTML(ctx, timeline)
+		}()
+	}
+
+	var passOutput io.Writer
+	if !dontPassOutput {
+		passOutput = os.Stderr
+	}
+
+	// every input is parsed separately, so the time between runs is not counted as their duration
+	runs := make([]parse.ParseResult, 0, len(readers))
+	for _, r := range readers {
+		// reports contain all tests, the duration cutoff is applied only to charts
+		run, err := parse.Parse(r, parse.Options{
+			Output:            passOutput,
+			OnUpdate:          onUpdate,
+			KeepAllExecutions: true,
+			Reruns:            rerunFails > 0,
+		})
+		if err != nil {
+			slog.Error("Error parsing test output", "err", err)
+		}
+		runs = append(runs, run)
+	}
+
+	fullResult := runs[0]
+	if len(runs) > 1 {
+		fullResult = parse.Merge(runs...)
+	}
+	result := fullResult.WithDurationCutoff(testDurationCutoffDuration)
+
+	exitCode := finish()
 
 	if checkClosing(ctx) {
 		return
 	}
 
-	if printHTML {
+	violations := checkBudgets(fullResult, budgets)
+	if len(violations) > 0 {
+		writeBudgetReport(os.Stderr, violations)
+	}
+
+	if parallelismReport {
+		writeParallelismReport(os.Stderr, analyzeParallelism(fullResult, gomaxprocs, testParallel()))
+		writeParallelSuggestions(os.Stderr, suggestParallelTests(fullResult, testParallel()), 20)
+	}
+
+	if criticalPathReport {
+		writeCriticalPath(os.Stderr, fullResult, criticalPath(fullResult))
+	}
+
+	if flakyReport {
+		writeFlakyReport(os.Stderr, findFlakyTests(fullResult))
+	}
+
+	if historyDir != "" {
+		commit, branch := gitMetadata()
+		if err := appendHistory(historyDir, newHistoryRun(fullResult, commit, branch)); err != nil {
+			slog.Error("Error writing history", "err", err)
+		}
+	}
+
+	if junitFile != "" {
+		if err := writeJUnitFile(fullResult, junitFile); err != nil {
+			slog.Error("Error writing junit report", "err", err)
+		}
+	}
+
+	switch {
+	case outputFormat == formatJUnit:
+		report, err := renderJUnit(fullResult)
+		if err != nil {
+			slog.Error("Error rendering junit report", "err", err)
+			return
+		}
+		_, _ = os.Stdout.Write(report)
+	case outputFormat == formatChromeTrace:
+		trace, err := renderChromeTrace(result)
+		if err != nil {
+			slog.Error("Error rendering chrome trace", "err", err)
+			return
+		}
+		_, _ = os.Stdout.Write(trace)
+	case outputFormat == formatSVG:
+		_, _ = os.Stdout.Write([]byte(renderSVG(result, generateCharts(result))))
+	case asciiMode:
+		_, _ = fmt.Fprint(os.Stdout, renderASCII(result, generateCharts(result), asciiWidth(), asciiColor()))
+	case tuiMode:
+		if err := runTUI(ctx, result, generateCharts(result)); err != nil {
+			slog.Error("Error running terminal timeline", "err", err)
+		}
+	case printHTML:
 		charts := generateCharts(result)
-		html, err := render(result, charts, false)
+		html, err := render(result, charts, parallelismFromFlags(), false, false)
 		if err != nil {
 			slog.Error("Error rendering html", "err", err)
 			return
 		}
 		_, _ = os.Stdout.Write([]byte(html))
-	} else {
-		serveHTML(ctx, result)
+	default:
+		timeline.Finish(result)
+
+		if liveMode {
+			<-serverDone
+		} else {
+			serveHTML(ctx, timeline)
+		}
 	}
 
 	if exitCode != 0 {
 		os.Exit(exitCode)
 	}
-	if result.Failed {
+	// with -rerun-fails, failures fixed by re-runs are reflected only in the exit code of go test
+	if (result.Failed && rerunFails == 0) || len(violations) > 0 {
 		os.Exit(1)
 	}
 }
 
-func newReader(ctx context.Context) (io.Reader, func(), int, bool) {
+func setupLogger() {
+	logLevel := slog.LevelInfo
+	if debug {
+		logLevel = slog.LevelDebug
+	}
+
+	slog.SetDefault(slog.New(
+		tint.NewHandler(os.Stderr, &tint.Options{
+			Level:      logLevel,
+			TimeFormat: time.Kitchen,
+		}),
+	))
+}
+
+// stringsFlag is a flag which can be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// newReaders returns readers with test2json output, one for every input file or a single one otherwise,
+// and a function that should be called after the whole output was consumed. The returned function
+// releases resources and returns the exit code of the test command (if vgt was running it).
+func newReaders(ctx context.Context) ([]io.Reader, func() int, bool) {
 	fi, err := os.Stdin.Stat()
 	if err != nil {
 		slog.Error("Error getting stdin stat", "err", err)
-		return nil, nil, 0, false
+		return nil, nil, false
 	}
 
 	isPipe := (fi.Mode() & os.ModeCharDevice) == 0
-	readFromFile := fromFile != ""
+	readFromFile := len(fromFiles) > 0
+
+	if rerunFails > 0 && (isPipe || readFromFile) {
+		slog.Error("-rerun-fails can be used only when vgt runs go test")
+		return nil, nil, false
+	}
 
 	if isPipe && readFromFile {
 		slog.Error("Can't read from file and stdin at the same time")
-		return nil, nil, 0, false
+		return nil, nil, false
 	}
 
 	if readFromFile {
-		f, err := os.Open(fromFile)
-		if err != nil {
-			slog.Error("Error opening file", "err", err)
-			return nil, nil, 0, false
+		var files []*os.File
+		closeFiles := func() int {
+			for _, f := range files {
+				_ = f.Close()
+			}
+			return 0
 		}
 
-		return f, func() {
-			_ = f.Close()
-		}, 0, true
+		var readers []io.Reader
+		for _, name := range fromFiles {
+			f, err := os.Open(name)
+			if err != nil {
+				slog.Error("Error opening file", "err", err)
+				closeFiles()
+				return nil, nil, false
+			}
+			files = append(files, f)
+			readers = append(readers, f)
+		}
+
+		return readers, closeFiles, true
 	}
 
 	if isPipe {
 		sr, err := cancelreader.NewReader(os.Stdin)
 		if err != nil {
 			slog.Error("Error creating cancel reader", "err", err)
-			return nil, nil, 0, false
+			return nil, nil, false
 		}
 
 		go func() {
@@ -141,43 +380,29 @@ func newReader(ctx context.Context) (io.Reader, func(), int, bool) {
 			sr.Cancel()
 		}()
 
-		return sr, func() {}, 0, true
+		return []io.Reader{sr}, func() int { return 0 }, true
 	}
 
-	r := bytes.NewBuffer([]byte{})
-
-	command := append([]string{"go", "test", "-json"}, flag.Args()...)
-
-	slog.Info("Running go test", "command", command)
+	runner := newGoTestRunner(ctx, flag.Args(), rerunFails)
 
-	cmd := exec.Command(command[0], command[1:]...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = io.MultiWriter(r, os.Stdout)
-	cmd.Stderr = os.Stderr
-
-	var exitCode int
-
-	err = cmd.Run()
-	var exitErr *exec.ExitError
-	if err != nil {
-		if errors.As(err, &exitErr) {
-			// this is expected - tests failed
-			slog.Info("Error running go test", "err", err)
-			exitCode = exitErr.ExitCode()
-		} else {
-			slog.Error("Error running go test", "err", err)
-			return nil, nil, 0, false
-		}
+	// output is consumed by the parser while tests are still running,
+	// so we don't need to keep the whole output in memory
+	var r io.Reader = runner
+	if !printsToStdout() {
+		r = io.TeeReader(runner, os.Stdout)
 	}
 
-	go func() {
-		<-ctx.Done()
-		_ = cmd.Process.Kill()
-	}()
+	return []io.Reader{r}, func() int {
+		runner.Close()
+
+		return runner.ExitCode()
+	}, true
+}
 
-	return r, func() {
-		_, _ = cmd.Process.Wait()
-	}, exitCode, true
+// printsToStdout returns true if results are printed to stdout,
+// so it can't be used for anything else.
+func printsToStdout() bool {
+	return printHTML || asciiMode || outputFormat != formatHTML
 }
 
 func checkClosing(ctx context.Context) bool {
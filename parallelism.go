@@ -0,0 +1,363 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// concurrencyPoint is the number of running tests since Time (relative to the start of the run).
+type concurrencyPoint struct {
+	Time    time.Duration
+	Running int
+}
+
+// concurrency describes how many tests were running over time.
+type concurrency struct {
+	Points []concurrencyPoint
+	// Busy is the sum of durations of all running tests.
+	Busy time.Duration
+	Peak int
+}
+
+// Average returns the average number of tests running in parallel during wall.
+func (c concurrency) Average(wall time.Duration) float64 {
+	if wall <= 0 {
+		return 0
+	}
+
+	return float64(c.Busy) / float64(wall)
+}
+
+// timeWith returns how long the number of running tests was matching the condition.
+func (c concurrency) timeWith(end time.Duration, condition func(running int) bool) time.Duration {
+	var total time.Duration
+	for i, point := range c.Points {
+		next := end
+		if i+1 < len(c.Points) {
+			next = c.Points[i+1].Time
+		}
+		if condition(point.Running) {
+			total += next - point.Time
+		}
+	}
+
+	return total
+}
+
+// usedSlots returns the sum of durations of running tests, counting at most slots tests at once.
+func (c concurrency) usedSlots(end time.Duration, slots int) time.Duration {
+	var total time.Duration
+	for i, point := range c.Points {
+		next := end
+		if i+1 < len(c.Points) {
+			next = c.Points[i+1].Time
+		}
+		total += time.Duration(min(point.Running, slots)) * (next - point.Time)
+	}
+
+	return total
+}
+
+type packageParallelism struct {
+	Package string
+	// Duration is the time between the start of the first test and the end of the package.
+	Duration time.Duration
+	Average  float64
+	Peak     int
+	// SerialFraction is the fraction of Duration in which at most one test was running.
+	SerialFraction float64
+	// IdleFraction is the fraction of -parallel slots which were not used by any test.
+	IdleFraction float64
+	// Parallel is the value of the -parallel flag of go test, zero if it's unknown.
+	Parallel int
+}
+
+type parallelismAnalysis struct {
+	Concurrency concurrency
+	Wall        time.Duration
+	Average     float64
+
+	// Slots is the number of tests which could run at once, the GOMAXPROCS of the machine running tests.
+	Slots int
+	// IdleFraction is the fraction of slots which were not used by any test.
+	IdleFraction float64
+
+	// Parallel is the value of the -parallel flag of go test, the limit of parallel tests in a package.
+	Parallel int
+	Packages []packageParallelism
+}
+
+// analyzeParallelism computes how many tests were running over time.
+// Only running segments of tests without subtests are counted: tests with subtests
+// are mostly waiting for them, and pauses are time spent waiting for other parallel tests.
+func analyzeParallelism(pr parse.ParseResult, slots, parallel int) parallelismAnalysis {
+	var leaves []parse.TestName
+	var walk func(nodes []*parse.TestNode)
+	walk = func(nodes []*parse.TestNode) {
+		for _, node := range nodes {
+			if len(node.Children) == 0 {
+				leaves = append(leaves, node.Test)
+			}
+			walk(node.Children)
+		}
+	}
+	walk(pr.TestTree())
+
+	var allSegments []parse.Segment
+	segmentsByPackage := map[string][]parse.Segment{}
+
+	for _, tn := range leaves {
+		runs, _ := pr.TestRuns.ByTestName(tn)
+		for _, run := range runs {
+			for _, segment := range run.Segments {
+				if segment.Kind != parse.SegmentKindRun || segment.End.IsZero() {
+					continue
+				}
+				allSegments = append(allSegments, segment)
+				segmentsByPackage[tn.Package] = append(segmentsByPackage[tn.Package], segment)
+			}
+		}
+	}
+
+	wall := pr.End.Sub(pr.Start)
+	c := concurrencyOf(allSegments, pr.Start)
+
+	analysis := parallelismAnalysis{
+		Concurrency: c,
+		Wall:        wall,
+		Average:     c.Average(wall),
+		Slots:       slots,
+		Parallel:    parallel,
+	}
+	if slots > 0 && wall > 0 {
+		analysis.IdleFraction = 1 - float64(c.usedSlots(wall, slots))/float64(time.Duration(slots)*wall)
+	}
+
+	for pkg, segments := range segmentsByPackage {
+		start := slices.MinFunc(segments, func(a, b parse.Segment) int { return a.Start.Compare(b.Start) }).Start
+		end := slices.MaxFunc(segments, func(a, b parse.Segment) int { return a.End.Compare(b.End) }).End
+
+		if execution, ok := pr.Packages[pkg]; ok && !execution.FirstTestStart.IsZero() && !execution.End.IsZero() {
+			// tests of re-run packages could run before the latest execution of the package
+			if execution.FirstTestStart.Before(start) {
+				start = execution.FirstTestStart
+			}
+			if execution.End.After(end) {
+				end = execution.End
+			}
+		}
+
+		duration := end.Sub(start)
+		if duration <= 0 {
+			continue
+		}
+
+		pc := concurrencyOf(segments, start)
+		serial := pc.timeWith(duration, func(running int) bool { return running <= 1 })
+
+		p := packageParallelism{
+			Package:        pkg,
+			Duration:       duration,
+			Average:        pc.Average(duration),
+			Peak:           pc.Peak,
+			SerialFraction: float64(serial) / float64(duration),
+			Parallel:       parallel,
+		}
+		if parallel > 0 {
+			p.IdleFraction = 1 - float64(pc.usedSlots(duration, parallel))/float64(time.Duration(parallel)*duration)
+		}
+
+		analysis.Packages = append(analysis.Packages, p)
+	}
+
+	sort.Slice(analysis.Packages, func(i, j int) bool {
+		if analysis.Packages[i].Duration != analysis.Packages[j].Duration {
+			return analysis.Packages[i].Duration > analysis.Packages[j].Duration
+		}
+		return analysis.Packages[i].Package < analysis.Packages[j].Package
+	})
+
+	return analysis
+}
+
+func concurrencyOf(segments []parse.Segment, start time.Time) concurrency {
+	type event struct {
+		time  time.Time
+		delta int
+	}
+
+	events := make([]event, 0, len(segments)*2)
+	for _, segment := range segments {
+		events = append(events, event{segment.Start, 1}, event{segment.End, -1})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].time.Equal(events[j].time) {
+			return events[i].time.Before(events[j].time)
+		}
+		// tests which ended are not overlapping with tests started at the same time
+		return events[i].delta < events[j].delta
+	})
+
+	c := concurrency{
+		Points: []concurrencyPoint{{Time: 0, Running: 0}},
+	}
+	running := 0
+
+	for _, e := range events {
+		running += e.delta
+		c.Peak = max(c.Peak, running)
+
+		t := e.time.Sub(start)
+		if last := &c.Points[len(c.Points)-1]; last.Time == t {
+			last.Running = running
+		} else {
+			c.Points = append(c.Points, concurrencyPoint{Time: t, Running: running})
+		}
+	}
+
+	for _, segment := range segments {
+		c.Busy += segment.Duration()
+	}
+
+	return c
+}
+
+func (a parallelismAnalysis) Summary() string {
+	summary := fmt.Sprintf("Parallelism: average %.1f, peak %d", a.Average, a.Concurrency.Peak)
+	if a.Slots > 0 {
+		summary += fmt.Sprintf(", %.0f%% of %d CPU slots idle", a.IdleFraction*100, a.Slots)
+	}
+
+	return summary
+}
+
+// Summary is presented in details of the package.
+func (p packageParallelism) Summary() string {
+	summary := fmt.Sprintf(
+		"Parallelism: average %.1f, peak %d, at most one test running %.0f%% of the time",
+		p.Average,
+		p.Peak,
+		p.SerialFraction*100,
+	)
+	if p.Parallel > 0 {
+		summary += fmt.Sprintf(", %.0f%% of -parallel slots idle", p.IdleFraction*100)
+	}
+
+	return summary + "."
+}
+
+func writeParallelismReport(w io.Writer, a parallelismAnalysis) {
+	_, _ = fmt.Fprintf(w, "\n%s\n", a.Summary())
+
+	if len(a.Packages) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nPackages (-parallel=%d):\n", a.Parallel)
+	for _, p := range a.Packages {
+		_, _ = fmt.Fprintf(
+			w,
+			"  %-50s %10s  average %4.1f  peak %3d  serial %3.0f%%  idle %3.0f%%\n",
+			p.Package,
+			p.Duration.Round(time.Millisecond),
+			p.Average,
+			p.Peak,
+			p.SerialFraction*100,
+			p.IdleFraction*100,
+		)
+	}
+}
+
+// concurrencyChart returns a line chart of running tests, presented above the timeline.
+func concurrencyChart(a parallelismAnalysis) []map[string]any {
+	x := make([]float64, 0, len(a.Concurrency.Points)+1)
+	y := make([]int, 0, len(a.Concurrency.Points)+1)
+	for _, point := range a.Concurrency.Points {
+		x = append(x, point.Time.Seconds())
+		y = append(y, point.Running)
+	}
+	x = append(x, a.Wall.Seconds())
+	y = append(y, 0)
+
+	traces := []map[string]any{{
+		"type":      "scatter",
+		"mode":      "lines",
+		"x":         x,
+		"y":         y,
+		"line":      map[string]any{"shape": "hv", "color": "rgb(31, 119, 180)"},
+		"fill":      "tozeroy",
+		"name":      "running tests",
+		"hoverinfo": "x+y",
+	}}
+
+	if a.Slots > 0 {
+		traces = append(traces, map[string]any{
+			"type":      "scatter",
+			"mode":      "lines",
+			"x":         []float64{0, a.Wall.Seconds()},
+			"y":         []int{a.Slots, a.Slots},
+			"line":      map[string]any{"dash": "dash", "color": "rgb(150, 150, 150)"},
+			"name":      "CPU slots",
+			"hoverinfo": "name+y",
+		})
+	}
+
+	return traces
+}
+
+// parallelismOptions describe how tests were run, they are zero if they are unknown
+// (for example, when runs are compared by the diff command).
+type parallelismOptions struct {
+	// Slots is the GOMAXPROCS of the machine running tests.
+	Slots int
+	// Parallel is the value of the -parallel flag of go test.
+	Parallel int
+}
+
+// parallelismFromFlags returns parallelism options set by flags of the main command.
+func parallelismFromFlags() parallelismOptions {
+	return parallelismOptions{Slots: gomaxprocs, Parallel: testParallel()}
+}
+
+// testParallel returns the limit of parallel tests in a package used for the analysis.
+func testParallel() int {
+	if testParallelFlag > 0 {
+		return testParallelFlag
+	}
+	if len(fromFiles) == 0 {
+		if parallel := goTestParallel(flag.Args()); parallel > 0 {
+			return parallel
+		}
+	}
+
+	// go test defaults -parallel to GOMAXPROCS
+	return gomaxprocs
+}
+
+// goTestParallel returns the value of -parallel passed to go test, or zero if it was not passed.
+func goTestParallel(args []string) int {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "parallel" && name != "test.parallel" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+
+		parallel, err := strconv.Atoi(value)
+		if err == nil {
+			return parallel
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeParallelism(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/a"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/b"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/a"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/b"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:16.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:16.000000+02:00","Action":"pass","Package":"example.com/pkg"}
+`
+	analysis := analyzeParallelism(mustParse(t, input), 4, 2)
+
+	require.Equal(t, 4*time.Second, analysis.Wall)
+	require.Equal(t, 2, analysis.Concurrency.Peak, "TestA is waiting for its subtests, so it's not counted")
+	require.InDelta(t, 1.25, analysis.Average, 0.001)
+	require.InDelta(t, 1-5.0/16, analysis.IdleFraction, 0.001)
+
+	require.Len(t, analysis.Packages, 1)
+	pkg := analysis.Packages[0]
+	require.InDelta(t, 0.75, pkg.SerialFraction, 0.001)
+	require.InDelta(t, 1-5.0/8, pkg.IdleFraction, 0.001)
+}
+
+func TestAnalyzeParallelism_unknown_settings(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg"}
+`
+	analysis := analyzeParallelism(mustParse(t, input), 0, 0)
+
+	require.Equal(t, "Parallelism: average 1.0, peak 1", analysis.Summary())
+
+	require.Len(t, analysis.Packages, 1)
+	require.Equal(
+		t,
+		"Parallelism: average 1.0, peak 1, at most one test running 100% of the time.",
+		analysis.Packages[0].Summary(),
+	)
+}
+
+func TestGoTestParallel(t *testing.T) {
+	require.Equal(t, 4, goTestParallel([]string{"./...", "-parallel", "4"}))
+	require.Equal(t, 8, goTestParallel([]string{"-count=1", "--parallel=8", "./..."}))
+	require.Equal(t, 0, goTestParallel([]string{"./..."}))
+}
@@ -0,0 +1,398 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type TestName struct {
+	Package  string
+	TestName string
+}
+
+func (t TestName) String() string {
+	return fmt.Sprintf("%s/%s", t.Package, t.TestName)
+}
+
+// Parent returns the name of the test which started the subtest.
+func (t TestName) Parent() (TestName, bool) {
+	i := strings.LastIndex(t.TestName, "/")
+	if i == -1 {
+		return TestName{}, false
+	}
+
+	return TestName{Package: t.Package, TestName: t.TestName[:i]}, true
+}
+
+type TestExecution struct {
+	Test TestName
+
+	// Attempt is the number of the test execution, starting from 1.
+	// Tests are executed multiple times when running with -count or when they are retried.
+	Attempt int
+
+	Start time.Time
+	End   time.Time
+
+	// Elapsed is the duration reported by go test (the same as in "--- PASS: TestName (0.42s)").
+	// It's zero if it was not reported.
+	Elapsed time.Duration
+
+	// Segments are consecutive intervals in which the test was running or paused.
+	// The last segment has zero End while the test is still in progress.
+	Segments []Segment
+
+	Status TestStatus
+	// SkipReason is the message passed to t.Skip, if the test was skipped.
+	SkipReason string
+
+	// Output contains lines printed by the test, including go test's "=== RUN" and "--- PASS" lines.
+	// Only the last Options.MaxOutputLines lines are kept.
+	Output []string
+	// OmittedOutputLines is the number of the first output lines which were not kept.
+	OmittedOutputLines int
+}
+
+// Duration returns how long the test was running.
+// It's the elapsed time reported by go test, or the time computed from event timestamps
+// if go test didn't report it.
+func (t TestExecution) Duration() time.Duration {
+	if t.Start.IsZero() || t.End.IsZero() {
+		return 0
+	}
+
+	if t.Elapsed > 0 {
+		return t.Elapsed
+	}
+
+	return t.TimestampDuration()
+}
+
+// TimestampDuration returns how long the test was running according to event timestamps,
+// excluding time when it was paused.
+func (t TestExecution) TimestampDuration() time.Duration {
+	if t.Start.IsZero() || t.End.IsZero() {
+		return 0
+	}
+
+	if len(t.Segments) == 0 {
+		return t.WallDuration()
+	}
+
+	var d time.Duration
+	for _, segment := range t.Segments {
+		if segment.Kind == SegmentKindRun {
+			d += segment.Duration()
+		}
+	}
+
+	return d
+}
+
+// HasDurationDiscrepancy returns true if the elapsed time reported by go test
+// significantly differs from the time computed from event timestamps.
+func (t TestExecution) HasDurationDiscrepancy() bool {
+	if t.Elapsed <= 0 {
+		return false
+	}
+
+	diff := t.TimestampDuration() - t.Elapsed
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff > durationDiscrepancyMin && float64(diff) > float64(t.Elapsed)*durationDiscrepancyRatio
+}
+
+const (
+	// go test reports elapsed time with 10ms precision, and timestamps are affected by output buffering
+	durationDiscrepancyMin   = 50 * time.Millisecond
+	durationDiscrepancyRatio = 0.1
+)
+
+// WallDuration returns time between the start and the end of the test, including pauses.
+func (t TestExecution) WallDuration() time.Duration {
+	if t.Start.IsZero() || t.End.IsZero() {
+		return 0
+	}
+
+	return t.End.Sub(t.Start)
+}
+
+// Pauses returns all intervals in which the test was paused.
+func (t TestExecution) Pauses() []Segment {
+	var pauses []Segment
+	for _, segment := range t.Segments {
+		if segment.Kind == SegmentKindPause {
+			pauses = append(pauses, segment)
+		}
+	}
+
+	return pauses
+}
+
+func (t TestExecution) openSegment(kind SegmentKind, at time.Time) TestExecution {
+	t = t.closeSegment(at)
+	t.Segments = append(t.Segments, Segment{Kind: kind, Start: at})
+	return t
+}
+
+func (t TestExecution) closeSegment(at time.Time) TestExecution {
+	if len(t.Segments) == 0 {
+		return t
+	}
+
+	// segments are shared with updates sent to the Options.OnUpdate callback
+	t.Segments = slices.Clone(t.Segments)
+
+	last := &t.Segments[len(t.Segments)-1]
+	if last.End.IsZero() {
+		last.End = at
+	}
+
+	return t
+}
+
+func (t TestExecution) appendOutput(line string, maxLines int) TestExecution {
+	t.Output = appendOutput(t.Output, &t.OmittedOutputLines, line, maxLines)
+	return t
+}
+
+// appendOutput appends the line to output, keeping only the last maxLines lines.
+// Dropped lines are added to omitted.
+func appendOutput(output []string, omitted *int, line string, maxLines int) []string {
+	output = append(output, line)
+	if len(output) > maxLines {
+		*omitted += len(output) - maxLines
+		output = output[len(output)-maxLines:]
+	}
+
+	return output
+}
+
+// shift moves times of the execution by offset.
+func (t TestExecution) shift(offset time.Duration) TestExecution {
+	t.Start = shiftTime(t.Start, offset)
+	t.End = shiftTime(t.End, offset)
+
+	t.Segments = slices.Clone(t.Segments)
+	for i := range t.Segments {
+		t.Segments[i].Start = shiftTime(t.Segments[i].Start, offset)
+		t.Segments[i].End = shiftTime(t.Segments[i].End, offset)
+	}
+
+	return t
+}
+
+// shiftTime moves the time by offset, zero time means unknown and is not moved.
+func shiftTime(t time.Time, offset time.Duration) time.Time {
+	if t.IsZero() {
+		return t
+	}
+
+	return t.Add(offset)
+}
+
+func (t TestExecution) isPaused() bool {
+	if len(t.Segments) == 0 {
+		return false
+	}
+
+	last := t.Segments[len(t.Segments)-1]
+	return last.Kind == SegmentKindPause && last.End.IsZero()
+}
+
+type SegmentKind string
+
+const (
+	SegmentKindRun   SegmentKind = "run"
+	SegmentKindPause SegmentKind = "pause"
+)
+
+type Segment struct {
+	Kind SegmentKind
+
+	Start time.Time
+	End   time.Time
+}
+
+func (s Segment) Duration() time.Duration {
+	if s.Start.IsZero() || s.End.IsZero() {
+		return 0
+	}
+
+	return s.End.Sub(s.Start)
+}
+
+type TestStatus string
+
+const (
+	TestStatusRunning TestStatus = "running"
+	TestStatusPassed  TestStatus = "passed"
+	TestStatusFailed  TestStatus = "failed"
+	TestStatusSkipped TestStatus = "skipped"
+)
+
+// TestCounts contains the number of test executions by status.
+type TestCounts struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+func (c *TestCounts) add(status TestStatus) {
+	switch status {
+	case TestStatusPassed:
+		c.Passed++
+	case TestStatusFailed:
+		c.Failed++
+	case TestStatusSkipped:
+		c.Skipped++
+	}
+}
+
+// TestExecutions contains all attempts of the test, ordered by the attempt number.
+type TestExecutions map[TestName][]TestExecution
+
+func (t TestExecutions) MarshalJSON() ([]byte, error) {
+	m := map[string][]TestExecution{}
+
+	for k, v := range t {
+		m[k.String()] = v
+	}
+
+	return json.Marshal(m)
+}
+
+// startAttempt adds a new attempt of the test.
+func (t TestExecutions) startAttempt(testName TestName, updateFn func(TestExecution) TestExecution) {
+	t[testName] = append(t[testName], updateFn(TestExecution{
+		Test:    testName,
+		Attempt: len(t[testName]) + 1,
+	}))
+}
+
+// update updates the latest attempt of the test.
+func (t TestExecutions) update(testName TestName, updateFn func(TestExecution) TestExecution) {
+	attempt := len(t[testName])
+	if attempt == 0 {
+		attempt = 1
+	}
+
+	t.updateAttempt(testName, attempt, updateFn)
+}
+
+// updateAttempt updates the given attempt of the test, creating it if it doesn't exist yet.
+func (t TestExecutions) updateAttempt(testName TestName, attempt int, updateFn func(TestExecution) TestExecution) {
+	for i, execution := range t[testName] {
+		if execution.Attempt == attempt {
+			t[testName][i] = updateFn(execution)
+			return
+		}
+	}
+
+	t[testName] = append(t[testName], updateFn(TestExecution{
+		Test:    testName,
+		Attempt: attempt,
+	}))
+}
+
+// Latest returns the latest attempt of the test.
+func (t TestExecutions) Latest(testName TestName) (TestExecution, bool) {
+	executions := t[testName]
+	if len(executions) == 0 {
+		return TestExecution{}, false
+	}
+
+	return executions[len(executions)-1], true
+}
+
+func (t TestExecutions) ByTestName(testName TestName) ([]TestExecution, bool) {
+	te, ok := t[testName]
+	return te, ok
+}
+
+func (t TestExecutions) AsSlice() []TestExecution {
+	slice := make([]TestExecution, 0, len(t))
+
+	for _, executions := range t {
+		slice = append(slice, executions...)
+	}
+	return slice
+}
+
+// Clone returns a copy which can be modified without affecting t.
+func (t TestExecutions) Clone() TestExecutions {
+	c := make(TestExecutions, len(t))
+	for testName, executions := range t {
+		c[testName] = slices.Clone(executions)
+	}
+
+	return c
+}
+
+// Filter removes attempts for which keep returns false.
+// Tests without any attempts left are removed.
+func (t TestExecutions) Filter(keep func(TestExecution) bool) {
+	for testName, executions := range t {
+		kept := executions[:0]
+		for _, execution := range executions {
+			if keep(execution) {
+				kept = append(kept, execution)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(t, testName)
+		} else {
+			t[testName] = kept
+		}
+	}
+}
+
+// DurationStats summarises durations of all attempts of a test.
+type DurationStats struct {
+	Attempts int
+
+	Min    time.Duration
+	Median time.Duration
+	Max    time.Duration
+}
+
+func (d DurationStats) String() string {
+	return fmt.Sprintf(
+		"%d attempts: min %s, median %s, max %s",
+		d.Attempts,
+		d.Min.Round(time.Millisecond),
+		d.Median.Round(time.Millisecond),
+		d.Max.Round(time.Millisecond),
+	)
+}
+
+func (t TestExecutions) DurationStats(testName TestName) DurationStats {
+	executions := t[testName]
+	if len(executions) == 0 {
+		return DurationStats{}
+	}
+
+	durations := make([]time.Duration, 0, len(executions))
+	for _, execution := range executions {
+		durations = append(durations, execution.Duration())
+	}
+	slices.Sort(durations)
+
+	median := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + durations[len(durations)/2]) / 2
+	}
+
+	return DurationStats{
+		Attempts: len(durations),
+		Min:      durations[0],
+		Median:   median,
+		Max:      durations[len(durations)-1],
+	}
+}
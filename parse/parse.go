@@ -0,0 +1,402 @@
+// Package parse parses the output of go test -json (test2json) into test executions
+// which can be presented on a timeline.
+package parse
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+type testOutput struct {
+	Time    time.Time `json:"Time"`
+	Action  action    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Output  string    `json:"Output"`
+	// Elapsed is set on pass, fail and skip events, in seconds.
+	Elapsed *float64 `json:"Elapsed"`
+
+	// FailedBuild is set on the package's fail event when its test binary could not be built.
+	FailedBuild string `json:"FailedBuild"`
+	// ImportPath is set instead of Package on build events (Go 1.24+).
+	ImportPath string `json:"ImportPath"`
+}
+
+type action string
+
+const (
+	actionRun   action = "run"
+	actionPause action = "pause"
+
+	actionPass action = "pass"
+	actionCont action = "cont"
+	actionFail action = "fail"
+	actionSkip action = "skip"
+
+	actionOutput action = "output"
+	actionStart  action = "start"
+
+	actionBuildOutput action = "build-output"
+	actionBuildFail   action = "build-fail"
+)
+
+func (t testOutput) IsZero() bool {
+	return t == testOutput{}
+}
+
+func (t testOutput) elapsed() time.Duration {
+	if t.Elapsed == nil {
+		return 0
+	}
+
+	return time.Duration(math.Round(*t.Elapsed * float64(time.Second)))
+}
+
+func statusFromAction(a action) TestStatus {
+	switch a {
+	case actionPass:
+		return TestStatusPassed
+	case actionSkip:
+		return TestStatusSkipped
+	default:
+		return TestStatusFailed
+	}
+}
+
+// skipReason extracts the message passed to t.Skip from the test's output.
+func skipReason(output []string) string {
+	skipLine := slices.IndexFunc(output, func(line string) bool {
+		return strings.HasPrefix(strings.TrimSpace(line), "--- SKIP")
+	})
+	if skipLine == -1 {
+		return ""
+	}
+
+	var lines []string
+	for i := skipLine - 1; i >= 0; i-- {
+		line := output[i]
+		if strings.HasPrefix(strings.TrimSpace(line), "===") {
+			break
+		}
+
+		if location := logLocationRegexp.FindString(line); location != "" {
+			lines = append(lines, strings.TrimPrefix(line, location))
+			break
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	slices.Reverse(lines)
+
+	if len(lines) == 0 {
+		// without -v, logs are printed after the "--- SKIP" line
+		for _, line := range output[skipLine+1:] {
+			if location := logLocationRegexp.FindString(line); location != "" {
+				return strings.TrimPrefix(line, location)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// logLocationRegexp matches the location prefix added by t.Log and t.Skip, like "    foo_test.go:12: ".
+var logLocationRegexp = regexp.MustCompile(`^\s*\S+\.go:\d+: `)
+
+// ExecutionUpdate is emitted through Options.OnUpdate every time a test execution changes.
+// Execution with zero End is still in progress.
+type ExecutionUpdate struct {
+	Execution TestExecution
+}
+
+// DefaultMaxLineSize is the default maximum size of a single line of test2json output.
+// Tests with a lot of output without newlines can produce very long lines.
+const DefaultMaxLineSize = 16 * 1024 * 1024
+
+// DefaultMaxOutputLines is the default number of output lines kept for a single test execution or package.
+const DefaultMaxOutputLines = 1000
+
+// Options configure Parse.
+type Options struct {
+	// Output receives every input line as it's parsed. It may be nil.
+	Output io.Writer
+
+	// DurationCutoff is the threshold under which test executions are removed from the result.
+	DurationCutoff time.Duration
+
+	// KeepAllExecutions disables DurationCutoff and removing test executions without duration.
+	// It's useful for reports which should contain every test, ParseResult.WithDurationCutoff
+	// can be used to filter the result later.
+	KeepAllExecutions bool
+
+	// OnUpdate is called every time a test execution changes, so results can be presented
+	// before the whole input is consumed. It may be nil.
+	OnUpdate func(ExecutionUpdate)
+
+	// MaxLineSize is the maximum size of a single input line, DefaultMaxLineSize if zero.
+	MaxLineSize int
+
+	// Reruns should be set when failed tests are re-run. The latest attempt of a test decides then
+	// whether it failed, and Counts contain only latest attempts.
+	Reruns bool
+
+	// MaxOutputLines is the number of the last output lines kept for every test execution and package
+	// (separately for build output), DefaultMaxOutputLines if zero.
+	// Output of long test runs would use unbounded memory otherwise.
+	MaxOutputLines int
+
+	// Logger is used for debug logs, slog.Default() if nil.
+	Logger *slog.Logger
+}
+
+// Parse reads go test -json output from r until EOF.
+// If reading the input fails, the result contains everything parsed until the error.
+func Parse(r io.Reader, opts Options) (ParseResult, error) {
+	maxLineSize := opts.MaxLineSize
+	if maxLineSize == 0 {
+		maxLineSize = DefaultMaxLineSize
+	}
+
+	maxOutputLines := opts.MaxOutputLines
+	if maxOutputLines == 0 {
+		maxOutputLines = DefaultMaxOutputLines
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	onUpdate := opts.OnUpdate
+
+	testRuns := make(TestExecutions)
+	packages := make(map[string]PackageExecution)
+	buildOutputs := make(map[string]buildOutput)
+
+	start := time.Time{}
+	end := time.Time{}
+
+	failed := false
+
+	notify := func(tn TestName) {
+		if onUpdate == nil || tn.TestName == "" {
+			return
+		}
+		te, ok := testRuns.Latest(tn)
+		if !ok {
+			return
+		}
+		// output can be big, and it's not presented before tests are finished
+		te.Output = nil
+		onUpdate(ExecutionUpdate{Execution: te})
+	}
+
+	i := 0
+
+	for scanner.Scan() {
+		s := scanner.Text()
+		i++
+
+		if opts.Output != nil {
+			_, _ = fmt.Fprintln(opts.Output, s)
+		}
+
+		if s == "" {
+			continue
+		}
+
+		var out testOutput
+		if err := json.Unmarshal([]byte(s), &out); err != nil {
+			logger.Debug("failed to unmarshal", "line", i, "error", err)
+			continue
+		}
+		if out.IsZero() {
+			logger.Debug("zero value", "line", i)
+			continue
+		}
+
+		if out.Action == actionFail || out.Action == actionBuildFail {
+			failed = true
+		}
+
+		switch out.Action {
+		case actionBuildOutput:
+			b := buildOutputs[out.ImportPath]
+			b.lines = appendOutput(b.lines, &b.omitted, strings.TrimSuffix(out.Output, "\n"), maxOutputLines)
+			buildOutputs[out.ImportPath] = b
+			continue
+		case actionBuildFail:
+			logger.Debug("build failed", "import_path", out.ImportPath)
+			continue
+		}
+
+		if !out.Time.IsZero() {
+			if start.IsZero() || out.Time.Before(start) {
+				start = out.Time
+			}
+			if end.IsZero() || out.Time.After(end) {
+				end = out.Time
+			}
+		}
+
+		if out.Package != "" {
+			pkg := packages[out.Package]
+			pkg.Package = out.Package
+			if out.Action == actionStart {
+				// the "start" event is emitted since Go 1.20, the package is started again
+				// when its failed tests are re-run, and only the latest execution is kept
+				pkg = PackageExecution{Package: out.Package, Start: out.Time}
+			} else if pkg.Start.IsZero() {
+				pkg.Start = out.Time
+			}
+			if out.Action == actionRun && pkg.FirstTestStart.IsZero() {
+				pkg.FirstTestStart = out.Time
+			}
+			packages[out.Package] = pkg
+		}
+
+		if out.Test == "" {
+			parsePackageEvent(packages, buildOutputs, out, maxOutputLines)
+			continue
+		}
+
+		tn := TestName{
+			Package:  out.Package,
+			TestName: out.Test,
+		}
+
+		switch out.Action {
+		case actionPause:
+			testRuns.update(tn, func(te TestExecution) TestExecution {
+				return te.openSegment(SegmentKindPause, out.Time)
+			})
+			notify(tn)
+		case actionCont:
+			latest, _ := testRuns.Latest(tn)
+			if !latest.isPaused() {
+				// go test prints "=== CONT" also when output switches back to the test
+				continue
+			}
+			testRuns.update(tn, func(te TestExecution) TestExecution {
+				return te.openSegment(SegmentKindRun, out.Time)
+			})
+			notify(tn)
+		case actionRun:
+			testRuns.startAttempt(tn, func(te TestExecution) TestExecution {
+				te.Start = out.Time
+				te.Status = TestStatusRunning
+				return te.openSegment(SegmentKindRun, out.Time)
+			})
+			notify(tn)
+		case actionPass, actionFail, actionSkip:
+			testRuns.update(tn, func(te TestExecution) TestExecution {
+				te.End = out.Time
+				te.Elapsed = out.elapsed()
+				te.Status = statusFromAction(out.Action)
+				if te.Status == TestStatusSkipped {
+					te.SkipReason = skipReason(te.Output)
+				}
+				return te.closeSegment(out.Time)
+			})
+			notify(tn)
+		case actionOutput:
+			testRuns.update(tn, func(te TestExecution) TestExecution {
+				return te.appendOutput(strings.TrimSuffix(out.Output, "\n"), maxOutputLines)
+			})
+		}
+	}
+
+	readErr := scanner.Err()
+	if readErr != nil {
+		readErr = fmt.Errorf("error reading input: %w", readErr)
+	}
+
+	counts := TestCounts{}
+	for tn, executions := range testRuns {
+		if tn.TestName == "" {
+			continue
+		}
+		if opts.Reruns {
+			// earlier attempts were re-run because they failed
+			executions = executions[len(executions)-1:]
+		}
+		for _, execution := range executions {
+			counts.add(execution.Status)
+		}
+	}
+
+	if opts.Reruns {
+		failed = counts.Failed > 0
+		for _, pkg := range packages {
+			// the latest status of packages with re-run tests is the status of the re-run
+			failed = failed || pkg.Status == TestStatusFailed || pkg.BuildFailed
+		}
+	}
+
+	maxDuration := time.Duration(0)
+	for _, execution := range testRuns.AsSlice() {
+		maxDuration = max(maxDuration, execution.Duration())
+	}
+
+	logger.Debug("parsed", "start", start, "end", end)
+
+	result := ParseResult{
+		TestRuns:    testRuns,
+		Packages:    packages,
+		Start:       start,
+		End:         end,
+		MaxDuration: maxDuration,
+		Counts:      counts,
+		Failed:      failed,
+		Reruns:      opts.Reruns,
+	}
+	if !opts.KeepAllExecutions {
+		result = result.withDurationCutoff(opts.DurationCutoff, logger)
+	}
+
+	return result, readErr
+}
+
+// buildOutput is the compiler output of a package which is built.
+type buildOutput struct {
+	lines   []string
+	omitted int
+}
+
+func parsePackageEvent(packages map[string]PackageExecution, buildOutputs map[string]buildOutput, out testOutput, maxOutputLines int) {
+	if out.Package == "" {
+		return
+	}
+
+	pkg := packages[out.Package]
+
+	switch out.Action {
+	case actionPass, actionFail, actionSkip:
+		pkg.End = out.Time
+		pkg.Elapsed = out.elapsed()
+		pkg.Status = statusFromAction(out.Action)
+
+		if out.FailedBuild != "" {
+			pkg.BuildFailed = true
+			// FailedBuild is the import path of the package which failed to build,
+			// it may be a dependency of the tested package
+			pkg.BuildOutput = buildOutputs[out.FailedBuild].lines
+			pkg.OmittedBuildOutputLines = buildOutputs[out.FailedBuild].omitted
+		}
+	case actionOutput:
+		pkg.Output = appendOutput(pkg.Output, &pkg.OmittedOutputLines, strings.TrimSuffix(out.Output, "\n"), maxOutputLines)
+	}
+
+	packages[out.Package] = pkg
+}
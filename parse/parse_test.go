@@ -0,0 +1,494 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA","Elapsed":0.2}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA","Elapsed":0.2}
+{"Time":"2024-09-18T21:02:12.700000+02:00","Action":"fail","Package":"example.com/pkg","Elapsed":0.7}
+`
+
+	parseResult := mustParse(t, input)
+
+	marshaled, err := json.Marshal(parseResult)
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{
+		"TestRuns": {
+			"example.com/pkg/TestA": [
+				{
+					"Test": {"Package": "example.com/pkg", "TestName": "TestA"},
+					"Attempt": 1,
+					"Start": "2024-09-18T21:02:12.1+02:00",
+					"End": "2024-09-18T21:02:12.4+02:00",
+					"Elapsed": 200000000,
+					"Segments": [
+						{"Kind": "run", "Start": "2024-09-18T21:02:12.1+02:00", "End": "2024-09-18T21:02:12.2+02:00"},
+						{"Kind": "pause", "Start": "2024-09-18T21:02:12.2+02:00", "End": "2024-09-18T21:02:12.3+02:00"},
+						{"Kind": "run", "Start": "2024-09-18T21:02:12.3+02:00", "End": "2024-09-18T21:02:12.4+02:00"}
+					],
+					"Status": "failed",
+					"SkipReason": "",
+					"Output": null,
+					"OmittedOutputLines": 0
+				},
+				{
+					"Test": {"Package": "example.com/pkg", "TestName": "TestA"},
+					"Attempt": 2,
+					"Start": "2024-09-18T21:02:12.4+02:00",
+					"End": "2024-09-18T21:02:12.6+02:00",
+					"Elapsed": 200000000,
+					"Segments": [
+						{"Kind": "run", "Start": "2024-09-18T21:02:12.4+02:00", "End": "2024-09-18T21:02:12.6+02:00"}
+					],
+					"Status": "passed",
+					"SkipReason": "",
+					"Output": null,
+					"OmittedOutputLines": 0
+				}
+			]
+		},
+		"Packages": {
+			"example.com/pkg": {
+				"Package": "example.com/pkg",
+				"Start": "2024-09-18T21:02:12+02:00",
+				"FirstTestStart": "2024-09-18T21:02:12.1+02:00",
+				"End": "2024-09-18T21:02:12.7+02:00",
+				"Elapsed": 700000000,
+				"Status": "failed",
+				"BuildFailed": false,
+				"BuildOutput": null,
+				"OmittedBuildOutputLines": 0,
+				"Output": null,
+				"OmittedOutputLines": 0
+			}
+		},
+		"Start": "2024-09-18T21:02:12+02:00",
+		"End": "2024-09-18T21:02:12.7+02:00",
+		"MaxDuration": 200000000,
+		"Counts": {"Passed": 1, "Failed": 1, "Skipped": 0},
+		"Failed": true,
+		"Reruns": false,
+		"MergedRuns": 0
+	}`, string(marshaled))
+}
+
+func mustParse(t *testing.T, input string) ParseResult {
+	t.Helper()
+
+	result, err := Parse(bytes.NewBufferString(input), Options{})
+	require.NoError(t, err)
+
+	return result
+}
+
+func TestParse_updates(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"pass","Package":"example.com/pkg"}
+`
+
+	var updates []ExecutionUpdate
+
+	result, err := Parse(bytes.NewBufferString(input), Options{
+		OnUpdate: func(u ExecutionUpdate) {
+			updates = append(updates, u)
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, updates, 4)
+	require.True(t, updates[0].Execution.End.IsZero())
+
+	last := updates[len(updates)-1]
+	latest, ok := result.TestRuns.Latest(last.Execution.Test)
+	require.True(t, ok)
+	require.Equal(t, latest, last.Execution)
+}
+
+func TestParse_output(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000100+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"    a_test.go:10: something went wrong\n"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"--- FAIL: TestA (0.80s)\n"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"output","Package":"example.com/pkg","Output":"FAIL\n"}
+`
+
+	result := mustParse(t, input)
+
+	run, ok := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestA"})
+	require.True(t, ok)
+	require.Equal(t, []string{
+		"=== RUN   TestA",
+		"    a_test.go:10: something went wrong",
+		"--- FAIL: TestA (0.80s)",
+	}, run.Output)
+	require.Equal(t, TestStatusFailed, run.Status)
+}
+
+func TestParse_output_limit(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000100+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"    a_test.go:10: line 1\n"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"    a_test.go:10: line 2\n"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"--- FAIL: TestA (0.80s)\n"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"output","Package":"example.com/pkg","Output":"TestMain log\n"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"output","Package":"example.com/pkg","Output":"FAIL\n"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"output","Package":"example.com/pkg","Output":"FAIL\texample.com/pkg\t0.9s\n"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"fail","Package":"example.com/pkg","Elapsed":0.9}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"# example.com/broken [example.com/broken.test]\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./a_test.go:10:2: undefined: foo\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./a_test.go:11:2: undefined: bar\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/broken"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+`
+
+	result, err := Parse(bytes.NewBufferString(input), Options{MaxOutputLines: 2})
+	require.NoError(t, err)
+
+	run, ok := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestA"})
+	require.True(t, ok)
+	require.Equal(t, []string{
+		"    a_test.go:10: line 2",
+		"--- FAIL: TestA (0.80s)",
+	}, run.Output)
+	require.Equal(t, 2, run.OmittedOutputLines)
+
+	pkg := result.Packages["example.com/pkg"]
+	require.Equal(t, []string{"FAIL", "FAIL\texample.com/pkg\t0.9s"}, pkg.Output)
+	require.Equal(t, 1, pkg.OmittedOutputLines)
+
+	broken := result.Packages["example.com/broken"]
+	require.Equal(t, []string{
+		"./a_test.go:10:2: undefined: foo",
+		"./a_test.go:11:2: undefined: bar",
+	}, broken.BuildOutput)
+	require.Equal(t, 1, broken.OmittedBuildOutputLines)
+}
+
+func TestParse_repeated_runs(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+`
+
+	result := mustParse(t, input)
+
+	tn := TestName{Package: "example.com/pkg", TestName: "TestA"}
+
+	runs, ok := result.TestRuns.ByTestName(tn)
+	require.True(t, ok)
+	require.Len(t, runs, 3)
+
+	for i, run := range runs {
+		require.Equal(t, i+1, run.Attempt)
+	}
+	require.Equal(t, TestStatusFailed, runs[1].Status)
+
+	require.Equal(t, DurationStats{
+		Attempts: 3,
+		Min:      100 * time.Millisecond,
+		Median:   200 * time.Millisecond,
+		Max:      300 * time.Millisecond,
+	}, result.TestRuns.DurationStats(tn))
+}
+
+func TestParse_pauses(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.650000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+`
+
+	result := mustParse(t, input)
+
+	run, ok := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestA"})
+	require.True(t, ok)
+
+	var kinds []SegmentKind
+	for _, segment := range run.Segments {
+		kinds = append(kinds, segment.Kind)
+	}
+	require.Equal(t, []SegmentKind{
+		SegmentKindRun,
+		SegmentKindPause,
+		SegmentKindRun,
+		SegmentKindPause,
+		SegmentKindRun,
+	}, kinds)
+
+	require.Len(t, run.Pauses(), 2)
+	require.Equal(t, 400*time.Millisecond, run.Duration())
+	require.Equal(t, 800*time.Millisecond, run.WallDuration())
+}
+
+func TestParseResult_TestTree(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.010000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/case_1"}
+{"Time":"2024-09-18T21:02:12.020000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/case_1/nested"}
+{"Time":"2024-09-18T21:02:12.030000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/case_1/nested"}
+{"Time":"2024-09-18T21:02:12.040000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/case_1"}
+{"Time":"2024-09-18T21:02:12.050000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.060000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.070000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/case_2"}
+{"Time":"2024-09-18T21:02:12.080000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/case_2"}
+{"Time":"2024-09-18T21:02:12.090000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+`
+
+	result := mustParse(t, input)
+
+	tree := result.TestTree()
+	require.Len(t, tree, 2)
+	require.Equal(t, "TestA", tree[0].Test.TestName)
+	require.Equal(t, 3, tree[0].Descendants())
+	require.Equal(t, "TestA/case_1/nested", tree[0].Children[0].Children[0].Test.TestName)
+
+	var names []string
+	for _, tn := range result.TestNamesInTreeOrder() {
+		names = append(names, tn.TestName)
+	}
+	require.Equal(t, []string{
+		"TestA",
+		"TestA/case_1",
+		"TestA/case_1/nested",
+		"TestA/case_2",
+		"TestB",
+	}, names)
+}
+
+func TestParseResult_TestNamesOrderedByStart_same_start(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/a","Test":"TestZ"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA/sub"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pass","Package":"example.com/a","Test":"TestZ"}
+`
+
+	// the order should not depend on map iteration
+	for range 20 {
+		result := mustParse(t, input)
+
+		var names []string
+		for _, tn := range result.TestNamesInTreeOrder() {
+			names = append(names, tn.String())
+		}
+		require.Equal(t, []string{
+			"example.com/a/TestZ",
+			"example.com/pkg/TestA",
+			"example.com/pkg/TestA/sub",
+			"example.com/pkg/TestB",
+			"example.com/pkg/TestC",
+		}, names)
+	}
+}
+
+func TestParse_packages(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"output","Package":"example.com/pkg","Output":"ok  \texample.com/pkg\t0.4s\n"}
+{"Time":"2024-09-18T21:02:12.400000+02:00","Action":"pass","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/notests"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"skip","Package":"example.com/notests"}
+`
+
+	result := mustParse(t, input)
+
+	require.Len(t, result.Packages, 1)
+
+	pkg := result.Packages["example.com/pkg"]
+	require.Equal(t, TestStatusPassed, pkg.Status)
+	require.Equal(t, 400*time.Millisecond, pkg.Duration())
+	require.Equal(t, 200*time.Millisecond, pkg.SetupDuration())
+	require.Equal(t, []string{"ok  \texample.com/pkg\t0.4s"}, pkg.Output)
+
+	require.Equal(t, []string{"example.com/pkg"}, result.PackagesOrderedByStart())
+}
+
+func TestParse_package_rerun(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"output","Package":"example.com/pkg","Output":"FAIL\n"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+{"Time":"2024-09-18T21:02:20.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:20.200000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:20.300000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:20.400000+02:00","Action":"output","Package":"example.com/pkg","Output":"ok  \texample.com/pkg\t0.4s\n"}
+{"Time":"2024-09-18T21:02:20.400000+02:00","Action":"pass","Package":"example.com/pkg","Elapsed":0.4}
+`
+
+	result := mustParse(t, input)
+
+	pkg := result.Packages["example.com/pkg"]
+	require.GreaterOrEqual(t, pkg.SetupDuration(), time.Duration(0))
+	require.Equal(t, 200*time.Millisecond, pkg.SetupDuration())
+	require.Equal(t, 400*time.Millisecond, pkg.Duration(), "only the latest execution of the package is kept")
+	require.Equal(t, TestStatusPassed, pkg.Status)
+	require.Equal(t, []string{"ok  \texample.com/pkg\t0.4s"}, pkg.Output)
+}
+
+func TestMerge(t *testing.T) {
+	first := mustParse(t, `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+`)
+	second := mustParse(t, `{"Time":"2024-09-18T22:00:00.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T22:00:00.200000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T22:00:00.300000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T22:00:00.400000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T22:00:00.500000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T22:00:00.600000+02:00","Action":"pass","Package":"example.com/pkg","Elapsed":0.6}
+`)
+
+	result := Merge(first, second)
+
+	require.Equal(t, 2, result.MergedRuns)
+	require.True(t, result.Failed)
+	require.Equal(t, TestCounts{Passed: 1, Failed: 1}, result.Counts)
+	require.Equal(t, first.Start, result.Start)
+	require.Equal(t, 600*time.Millisecond, result.End.Sub(result.Start), "time between runs should not be counted")
+
+	executions := result.TestRuns[TestName{Package: "example.com/pkg", TestName: "TestA"}]
+	require.Len(t, executions, 2)
+	require.Equal(t, 1, executions[0].Attempt)
+	require.Equal(t, TestStatusFailed, executions[0].Status)
+	require.Equal(t, 2, executions[1].Attempt)
+	require.Equal(t, TestStatusPassed, executions[1].Status)
+	require.Equal(t, first.Start.Add(200*time.Millisecond), executions[1].Start)
+	require.Equal(t, first.Start.Add(300*time.Millisecond), executions[1].Pauses()[0].Start)
+	require.Equal(t, 200*time.Millisecond, executions[1].Duration())
+
+	pkg := result.Packages["example.com/pkg"]
+	require.Equal(t, 600*time.Millisecond, pkg.Duration(), "the longest execution of the package should be kept")
+	require.Equal(t, 200*time.Millisecond, pkg.SetupDuration())
+
+	require.Equal(
+		t,
+		"2024-09-18T22:00:00.2+02:00",
+		second.TestRuns[TestName{Package: "example.com/pkg", TestName: "TestA"}][0].Start.Format(time.RFC3339Nano),
+		"merged runs should not be modified",
+	)
+}
+
+func TestParse_build_failure(t *testing.T) {
+	input := `{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"# example.com/broken [example.com/broken.test]\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"./broken_test.go:10:2: undefined: foo\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/broken"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"output","Package":"example.com/broken","Output":"FAIL\texample.com/broken [build failed]\n"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+`
+
+	result := mustParse(t, input)
+
+	require.True(t, result.Failed)
+	require.Equal(t, 1, result.BuildFailures())
+
+	pkg, ok := result.Packages["example.com/broken"]
+	require.True(t, ok)
+	require.True(t, pkg.BuildFailed)
+	require.Equal(t, []string{
+		"# example.com/broken [example.com/broken.test]",
+		"./broken_test.go:10:2: undefined: foo",
+	}, pkg.BuildOutput)
+}
+
+func TestParse_elapsed(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.430000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA","Elapsed":0.42}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB","Elapsed":0.29}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:14.500000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestC"}
+`
+
+	result := mustParse(t, input)
+
+	a, _ := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestA"})
+	require.Equal(t, 420*time.Millisecond, a.Duration())
+	require.Equal(t, 430*time.Millisecond, a.TimestampDuration())
+	require.False(t, a.HasDurationDiscrepancy())
+
+	b, _ := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestB"})
+	require.Equal(t, 290*time.Millisecond, b.Duration())
+	require.True(t, b.HasDurationDiscrepancy())
+
+	c, _ := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestC"})
+	require.Equal(t, 500*time.Millisecond, c.Duration())
+	require.False(t, c.HasDurationDiscrepancy())
+}
+
+func TestParse_skipped(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000100+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"    a_test.go:12: skipping in short mode\n"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"--- SKIP: TestA (0.10s)\n"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"skip","Package":"example.com/pkg","Test":"TestA","Elapsed":0.1}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.100010+02:00","Action":"skip","Package":"example.com/pkg","Test":"TestB","Elapsed":0}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestC","Elapsed":0.2}
+`
+
+	result := mustParse(t, input)
+
+	a, ok := result.TestRuns.Latest(TestName{Package: "example.com/pkg", TestName: "TestA"})
+	require.True(t, ok)
+	require.Equal(t, TestStatusSkipped, a.Status)
+	require.Equal(t, "skipping in short mode", a.SkipReason)
+
+	// TestB is below the duration cutoff, but it's still counted
+	require.Equal(t, TestCounts{Passed: 1, Skipped: 2}, result.Counts)
+	require.False(t, result.Failed)
+}
+
+func TestParse_keep_all_executions(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA","Elapsed":0}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.050000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB","Elapsed":0.05}
+{"Time":"2024-09-18T21:02:12.050000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:12.550000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestC","Elapsed":0.5}
+`
+
+	result, err := Parse(bytes.NewBufferString(input), Options{KeepAllExecutions: true})
+	require.NoError(t, err)
+	require.Len(t, result.TestRuns, 3)
+
+	filtered := result.WithDurationCutoff(100 * time.Millisecond)
+	require.Len(t, filtered.TestRuns, 1)
+	require.Equal(t, 500*time.Millisecond, filtered.MaxDuration)
+	require.Equal(t, 3, filtered.Counts.Passed)
+
+	require.Len(t, result.TestRuns, 3, "the original result should not be modified")
+}
@@ -0,0 +1,364 @@
+package parse
+
+import (
+	"log/slog"
+	"maps"
+	"sort"
+	"time"
+)
+
+// PackageExecution is the execution of the package's test binary.
+type PackageExecution struct {
+	Package string
+
+	// Start is the time of the first event of the package.
+	Start time.Time
+	// FirstTestStart is zero if the package didn't run any tests.
+	FirstTestStart time.Time
+	End            time.Time
+
+	// Elapsed is the duration reported by go test, zero if it was not reported.
+	Elapsed time.Duration
+
+	Status TestStatus
+
+	// BuildFailed is true if the test binary of the package could not be built.
+	BuildFailed bool
+	// BuildOutput contains compiler output of the failed build.
+	// Only the last Options.MaxOutputLines lines are kept.
+	BuildOutput []string
+	// OmittedBuildOutputLines is the number of the first build output lines which were not kept.
+	OmittedBuildOutputLines int
+
+	// Output contains lines printed outside of tests, like the final "ok" or "FAIL" line.
+	// Only the last Options.MaxOutputLines lines are kept.
+	Output []string
+	// OmittedOutputLines is the number of the first output lines which were not kept.
+	OmittedOutputLines int
+}
+
+func (p PackageExecution) Duration() time.Duration {
+	if p.Start.IsZero() || p.End.IsZero() {
+		return 0
+	}
+
+	return p.End.Sub(p.Start)
+}
+
+// SetupDuration returns time between the start of the package and its first test,
+// which is spent on initialisation of the test binary (including TestMain and init functions).
+func (p PackageExecution) SetupDuration() time.Duration {
+	if p.Start.IsZero() {
+		return 0
+	}
+	if p.FirstTestStart.IsZero() {
+		return p.Duration()
+	}
+
+	return p.FirstTestStart.Sub(p.Start)
+}
+
+// shift moves times of the execution by offset.
+func (p PackageExecution) shift(offset time.Duration) PackageExecution {
+	p.Start = shiftTime(p.Start, offset)
+	p.FirstTestStart = shiftTime(p.FirstTestStart, offset)
+	p.End = shiftTime(p.End, offset)
+
+	return p
+}
+
+type ParseResult struct {
+	TestRuns TestExecutions
+	Packages map[string]PackageExecution
+
+	Start time.Time
+	End   time.Time
+
+	MaxDuration time.Duration
+
+	// Counts include also tests which were not charted because of the duration cutoff.
+	Counts TestCounts
+
+	Failed bool
+
+	// Reruns is true if failed tests were re-run, see Options.Reruns.
+	Reruns bool
+
+	// MergedRuns is the number of runs merged by Merge, zero if the result wasn't merged.
+	// Merged runs overlap on the timeline, so the result doesn't describe how a single run
+	// was executed (like its parallelism or critical path).
+	MergedRuns int
+}
+
+// Merge merges results of separate runs of tests, executions of a test from all runs become its attempts
+// (like with go test -count). Runs are shifted to start at the same time as the first run,
+// so durations don't include time between runs. Every package keeps its longest execution.
+func Merge(runs ...ParseResult) ParseResult {
+	merged := ParseResult{
+		TestRuns:   make(TestExecutions),
+		Packages:   make(map[string]PackageExecution),
+		MergedRuns: len(runs),
+	}
+
+	for _, run := range runs {
+		if merged.Start.IsZero() {
+			merged.Start = run.Start
+		}
+
+		var offset time.Duration
+		if !run.Start.IsZero() {
+			offset = merged.Start.Sub(run.Start)
+		}
+
+		if end := shiftTime(run.End, offset); end.After(merged.End) {
+			merged.End = end
+		}
+
+		for tn, executions := range run.TestRuns {
+			for _, execution := range executions {
+				execution = execution.shift(offset)
+				execution.Attempt = len(merged.TestRuns[tn]) + 1
+				merged.TestRuns[tn] = append(merged.TestRuns[tn], execution)
+			}
+		}
+
+		for name, pkg := range run.Packages {
+			if current, ok := merged.Packages[name]; !ok || pkg.Duration() > current.Duration() {
+				merged.Packages[name] = pkg.shift(offset)
+			}
+		}
+
+		merged.MaxDuration = max(merged.MaxDuration, run.MaxDuration)
+		merged.Counts.Passed += run.Counts.Passed
+		merged.Counts.Failed += run.Counts.Failed
+		merged.Counts.Skipped += run.Counts.Skipped
+		merged.Failed = merged.Failed || run.Failed
+	}
+
+	return merged
+}
+
+// TestFailed returns true if the test failed. When failed tests were re-run,
+// only its latest attempt is considered, otherwise any failed attempt fails the test.
+func (p ParseResult) TestFailed(tn TestName) bool {
+	executions := p.TestRuns[tn]
+	if p.Reruns && len(executions) > 0 {
+		executions = executions[len(executions)-1:]
+	}
+
+	for _, execution := range executions {
+		if execution.Status == TestStatusFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithDurationCutoff returns a copy of the result without packages and test executions
+// which didn't take longer than cutoff, and without test executions with no duration.
+// Counts are not changed.
+func (p ParseResult) WithDurationCutoff(cutoff time.Duration) ParseResult {
+	return p.withDurationCutoff(cutoff, slog.Default())
+}
+
+func (p ParseResult) withDurationCutoff(cutoff time.Duration, logger *slog.Logger) ParseResult {
+	packages := maps.Clone(p.Packages)
+	for pkg, execution := range packages {
+		if execution.Duration() <= cutoff && !execution.BuildFailed {
+			delete(packages, pkg)
+			logger.Debug("removed package below threshold", "package", pkg, "duration", execution.Duration())
+			continue
+		}
+
+		logger.Debug(
+			"parsed package",
+			"package", pkg,
+			"ran_from", execution.Start,
+			"to", execution.End,
+			"setup", execution.SetupDuration(),
+			"status", execution.Status,
+			"build_failed", execution.BuildFailed,
+		)
+	}
+
+	maxDuration := time.Duration(0)
+
+	testRuns := p.TestRuns.Clone()
+	testRuns.Filter(func(execution TestExecution) bool {
+		if execution.Duration() == 0 {
+			logger.Debug("removed invalid test run", "test", execution.Test, "attempt", execution.Attempt)
+			return false
+		}
+
+		if execution.Duration() <= cutoff {
+			logger.Debug(
+				"removed test run below threshold",
+				"test", execution.Test,
+				"attempt", execution.Attempt,
+				"duration", execution.Duration(),
+			)
+			return false
+		}
+
+		if execution.Test == (TestName{}) {
+			logger.Debug("removed test run with empty test name", "test", execution.Test)
+			return false
+		}
+
+		logger.Debug(
+			"parsed test",
+			"test", execution.Test,
+			"attempt", execution.Attempt,
+			"ran_from", execution.Start,
+			"to", execution.End,
+			"for", execution.Duration(),
+			"timestamp_duration", execution.TimestampDuration(),
+			"pauses", len(execution.Pauses()),
+			"status", execution.Status,
+		)
+
+		maxDuration = max(maxDuration, execution.Duration())
+		return true
+	})
+
+	p.Packages = packages
+	p.TestRuns = testRuns
+	p.MaxDuration = maxDuration
+
+	return p
+}
+
+// BuildFailures returns the number of packages which failed to build.
+func (p ParseResult) BuildFailures() int {
+	count := 0
+	for _, pkg := range p.Packages {
+		if pkg.BuildFailed {
+			count++
+		}
+	}
+
+	return count
+}
+
+// PackagesOrderedByStart returns names of all packages with recorded executions or tests, ordered by start.
+func (p ParseResult) PackagesOrderedByStart() []string {
+	starts := make(map[string]time.Time, len(p.Packages))
+
+	for pkg, execution := range p.Packages {
+		starts[pkg] = execution.Start
+	}
+	for _, execution := range p.TestRuns.AsSlice() {
+		if start, ok := starts[execution.Test.Package]; !ok || execution.Start.Before(start) {
+			starts[execution.Test.Package] = execution.Start
+		}
+	}
+
+	packages := make([]string, 0, len(starts))
+	for pkg := range starts {
+		packages = append(packages, pkg)
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		if starts[packages[i]].Equal(starts[packages[j]]) {
+			return packages[i] < packages[j]
+		}
+		return starts[packages[i]].Before(starts[packages[j]])
+	})
+
+	return packages
+}
+
+func (p ParseResult) TestNamesOrderedByStart() []TestName {
+	allExecutions := p.TestRuns.AsSlice()
+
+	// executions come from a map, so ties are broken to keep the order deterministic
+	sort.SliceStable(allExecutions, func(i, j int) bool {
+		a, b := allExecutions[i], allExecutions[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.Test.Package != b.Test.Package {
+			return a.Test.Package < b.Test.Package
+		}
+		if a.Test.TestName != b.Test.TestName {
+			return a.Test.TestName < b.Test.TestName
+		}
+		return a.Attempt < b.Attempt
+	})
+
+	testNames := make([]TestName, 0, len(p.TestRuns))
+	uniqTestNames := make(map[TestName]struct{}, len(p.TestRuns))
+
+	for _, execution := range allExecutions {
+		if _, ok := uniqTestNames[execution.Test]; !ok {
+			testNames = append(testNames, execution.Test)
+			uniqTestNames[execution.Test] = struct{}{}
+		}
+	}
+
+	return testNames
+}
+
+// TestNode is a test with its subtests.
+type TestNode struct {
+	Test     TestName
+	Children []*TestNode
+}
+
+// Descendants returns the number of all subtests of the test, including nested ones.
+func (n *TestNode) Descendants() int {
+	count := 0
+	for _, child := range n.Children {
+		count += 1 + child.Descendants()
+	}
+
+	return count
+}
+
+// TestTree returns top-level tests with their subtests, ordered by start.
+// If the parent of a subtest was not recorded (for example, it was below the duration cutoff),
+// the subtest is attached to its closest recorded ancestor.
+func (p ParseResult) TestTree() []*TestNode {
+	testNames := p.TestNamesOrderedByStart()
+
+	nodes := make(map[TestName]*TestNode, len(testNames))
+	for _, tn := range testNames {
+		nodes[tn] = &TestNode{Test: tn}
+	}
+
+	var roots []*TestNode
+
+	for _, tn := range testNames {
+		node := nodes[tn]
+
+		var parent *TestNode
+		for ancestor, hasAncestor := tn.Parent(); hasAncestor && parent == nil; ancestor, hasAncestor = ancestor.Parent() {
+			parent = nodes[ancestor]
+		}
+
+		if parent != nil {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots
+}
+
+// TestNamesInTreeOrder returns test names ordered by start, with subtests placed right after their parent.
+func (p ParseResult) TestNamesInTreeOrder() []TestName {
+	var testNames []TestName
+
+	var walk func(nodes []*TestNode)
+	walk = func(nodes []*TestNode) {
+		for _, node := range nodes {
+			testNames = append(testNames, node.Test)
+			walk(node.Children)
+		}
+	}
+	walk(p.TestTree())
+
+	return testNames
+}
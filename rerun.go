@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// goTestValueFlags are go test and build flags which take a value, used to find package patterns in arguments.
+var goTestValueFlags = map[string]bool{
+	"run": true, "skip": true, "count": true, "timeout": true, "parallel": true, "cpu": true, "shuffle": true,
+	"bench": true, "benchtime": true, "list": true, "fuzz": true, "fuzztime": true, "fuzzminimizetime": true,
+	"coverprofile": true, "covermode": true, "coverpkg": true, "cpuprofile": true, "memprofile": true,
+	"memprofilerate": true, "blockprofile": true, "blockprofilerate": true, "mutexprofile": true,
+	"mutexprofilefraction": true, "outputdir": true, "trace": true, "vet": true, "exec": true, "o": true,
+	"C": true, "p": true, "tags": true, "mod": true, "modfile": true, "overlay": true, "pgo": true, "pkgdir": true,
+	"toolexec": true, "asmflags": true, "compiler": true, "gccgoflags": true, "gcflags": true, "ldflags": true,
+	"installsuffix": true,
+}
+
+// splitGoTestArgs splits go test arguments to flags and package patterns.
+// Arguments after -args are passed to test binaries, so they are kept at the end of flags.
+func splitGoTestArgs(args []string) (flags []string, packages []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			return append(flags, args[i:]...), packages
+		}
+		if !strings.HasPrefix(arg, "-") {
+			packages = append(packages, arg)
+			continue
+		}
+
+		flags = append(flags, arg)
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if goTestValueFlags[name] && !hasValue && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+
+	return flags, packages
+}
+
+// goTestFlags returns flags from go test arguments, without package patterns and -run,
+// so they can be used for re-running tests of a single package.
+func goTestFlags(args []string) []string {
+	flags, _ := splitGoTestArgs(args)
+
+	var withoutRun []string
+	for i := 0; i < len(flags); i++ {
+		if flags[i] == "-args" || flags[i] == "--args" {
+			return append(withoutRun, flags[i:]...)
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(flags[i], "-"), "=")
+		if name != "run" {
+			withoutRun = append(withoutRun, flags[i])
+			continue
+		}
+		if !hasValue {
+			// skip the value
+			i++
+		}
+	}
+
+	return withoutRun
+}
+
+// roundResults tracks results of top-level tests and packages in a round of go test runs.
+type roundResults struct {
+	// failedTests are top-level tests which failed in their latest execution, by package
+	failedTests    map[string]map[string]bool
+	failedPackages map[string]bool
+}
+
+func newRoundResults() roundResults {
+	return roundResults{
+		failedTests:    map[string]map[string]bool{},
+		failedPackages: map[string]bool{},
+	}
+}
+
+func (r roundResults) track(line []byte) {
+	var event struct {
+		Action  string
+		Package string
+		Test    string
+	}
+	if err := json.Unmarshal(line, &event); err != nil || event.Package == "" {
+		return
+	}
+	if event.Action != "pass" && event.Action != "fail" && event.Action != "skip" {
+		return
+	}
+
+	if event.Test == "" {
+		r.failedPackages[event.Package] = event.Action == "fail"
+		return
+	}
+	if strings.Contains(event.Test, "/") {
+		return
+	}
+
+	if r.failedTests[event.Package] == nil {
+		r.failedTests[event.Package] = map[string]bool{}
+	}
+	r.failedTests[event.Package][event.Test] = event.Action == "fail"
+}
+
+// rerunCommands returns commands re-running failed tests, one per package.
+// It returns false if some package failed outside of tests (for example, it didn't build),
+// so re-running its tests can't fix it.
+func (r roundResults) rerunCommands(flags []string) ([][]string, bool) {
+	var packages []string
+	for pkg, failed := range r.failedPackages {
+		if failed {
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Strings(packages)
+
+	var commands [][]string
+	rerunnable := true
+
+	for _, pkg := range packages {
+		var tests []string
+		for test, failed := range r.failedTests[pkg] {
+			if failed {
+				tests = append(tests, regexp.QuoteMeta(test))
+			}
+		}
+		if len(tests) == 0 {
+			rerunnable = false
+			continue
+		}
+		sort.Strings(tests)
+
+		command := []string{"go", "test", "-json", "-run", "^(" + strings.Join(tests, "|") + ")$", pkg}
+		commands = append(commands, append(command, flags...))
+	}
+
+	return commands, rerunnable
+}
+
+// goTestRunner runs go test and re-runs failed tests up to reruns times.
+// Output of all runs is read as one stream, so the parser sees re-runs as next attempts of tests.
+type goTestRunner struct {
+	ctx    context.Context
+	flags  []string
+	reruns int
+
+	queue   [][]string
+	cmd     *exec.Cmd
+	stdout  *bufio.Reader
+	pending []byte
+
+	round roundResults
+	// exitCode is the highest exit code of go test in the current round
+	exitCode int
+	// finalExitCode is set when some failure can't be fixed by re-running tests
+	finalExitCode int
+}
+
+func newGoTestRunner(ctx context.Context, args []string, reruns int) *goTestRunner {
+	return &goTestRunner{
+		ctx:    ctx,
+		flags:  goTestFlags(args),
+		reruns: reruns,
+		queue:  [][]string{append([]string{"go", "test", "-json"}, args...)},
+		round:  newRoundResults(),
+	}
+}
+
+func (g *goTestRunner) start() error {
+	command := g.queue[0]
+	g.queue = g.queue[1:]
+
+	slog.Info("Running go test", "command", command)
+
+	cmd := exec.CommandContext(g.ctx, command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	g.cmd = cmd
+	g.stdout = bufio.NewReader(stdout)
+
+	return nil
+}
+
+func (g *goTestRunner) wait() {
+	err := g.cmd.Wait()
+	g.cmd = nil
+
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// this is expected - tests failed
+		slog.Info("Error running go test", "err", err)
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		slog.Error("Error running go test", "err", err)
+		code = 1
+	}
+	g.exitCode = max(g.exitCode, code)
+}
+
+// nextRound queues re-runs of tests which failed in the finished round.
+func (g *goTestRunner) nextRound() {
+	if g.exitCode == 0 {
+		return
+	}
+
+	commands, rerunnable := g.round.rerunCommands(g.flags)
+	if !rerunnable && g.finalExitCode == 0 {
+		g.finalExitCode = g.exitCode
+	}
+	if g.reruns == 0 || len(commands) == 0 || g.ctx.Err() != nil {
+		return
+	}
+
+	g.reruns--
+	g.queue = commands
+	g.round = newRoundResults()
+	g.exitCode = 0
+}
+
+func (g *goTestRunner) Read(p []byte) (int, error) {
+	for len(g.pending) == 0 {
+		if g.cmd == nil {
+			if len(g.queue) == 0 {
+				return 0, io.EOF
+			}
+			if err := g.start(); err != nil {
+				return 0, err
+			}
+		}
+
+		line, err := g.stdout.ReadBytes('\n')
+		g.round.track(line)
+		g.pending = line
+
+		if errors.Is(err, io.EOF) {
+			g.wait()
+			if len(g.queue) == 0 {
+				g.nextRound()
+			}
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+
+	return n, nil
+}
+
+// ExitCode returns the exit code reflecting final results of tests, after they were re-run.
+func (g *goTestRunner) ExitCode() int {
+	if g.finalExitCode != 0 {
+		return g.finalExitCode
+	}
+
+	return g.exitCode
+}
+
+// Close waits for the running go test without starting re-runs.
+// If parsing stopped early, go test could block on writing to the pipe, so its output is discarded.
+func (g *goTestRunner) Close() {
+	g.queue = nil
+	g.reruns = 0
+	_, _ = io.Copy(io.Discard, g)
+}
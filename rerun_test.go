@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+func TestGoTestFlags(t *testing.T) {
+	require.Equal(
+		t,
+		[]string{"-v", "-timeout", "10m", "-count=1", "-args", "-custom", "./x"},
+		goTestFlags([]string{"-v", "./...", "-run", "TestA", "-timeout", "10m", "-count=1", "example.com/pkg", "-args", "-custom", "./x"}),
+	)
+	require.Empty(t, goTestFlags([]string{"-run=TestA", "./..."}))
+}
+
+func TestRoundResults_rerunCommands(t *testing.T) {
+	input := `{"Action":"fail","Package":"example.com/a","Test":"TestA/sub"}
+{"Action":"fail","Package":"example.com/a","Test":"TestA"}
+{"Action":"pass","Package":"example.com/a","Test":"TestB"}
+{"Action":"fail","Package":"example.com/a","Test":"Test.C"}
+{"Action":"fail","Package":"example.com/a"}
+{"Action":"pass","Package":"example.com/b","Test":"TestD"}
+{"Action":"pass","Package":"example.com/b"}`
+
+	round := newRoundResults()
+	for _, line := range strings.Split(input, "\n") {
+		round.track([]byte(line))
+	}
+
+	commands, rerunnable := round.rerunCommands([]string{"-v"})
+	require.True(t, rerunnable)
+	require.Equal(t, [][]string{
+		{"go", "test", "-json", "-run", `^(TestA|Test\.C)$`, "example.com/a", "-v"},
+	}, commands)
+
+	round.track([]byte(`{"Action":"fail","Package":"example.com/build"}`))
+	_, rerunnable = round.rerunCommands(nil)
+	require.False(t, rerunnable, "package failed outside of tests")
+}
+
+func TestParse_passed_when_rerun(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"    a_test.go:10: unlucky\n"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestA","Elapsed":0.1}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"fail","Package":"example.com/pkg","Elapsed":0.2}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.100000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA","Elapsed":0.1}
+{"Time":"2024-09-18T21:02:13.200000+02:00","Action":"pass","Package":"example.com/pkg","Elapsed":0.2}
+`
+	tn := parse.TestName{Package: "example.com/pkg", TestName: "TestA"}
+
+	pr, err := parse.Parse(bytes.NewBufferString(input), parse.Options{KeepAllExecutions: true, Reruns: true})
+	require.NoError(t, err)
+
+	require.False(t, pr.Failed)
+	require.False(t, pr.TestFailed(tn))
+	require.Equal(t, parse.TestCounts{Passed: 1}, pr.Counts)
+
+	details := testDetailsByKey(pr)
+	require.False(t, details[tn.String()].Failed)
+	require.True(t, details[tn.String()].Attempts[0].Failed)
+
+	report, err := renderJUnit(pr)
+	require.NoError(t, err)
+	require.NotContains(t, string(report), "<failure")
+	require.Contains(t, string(report), `<property name="flaky" value="true"></property>`)
+	require.Contains(t, string(report), "unlucky")
+
+	withoutReruns := mustParse(t, input)
+	require.True(t, withoutReruns.Failed)
+	require.True(t, withoutReruns.TestFailed(tn))
+	require.Equal(t, parse.TestCounts{Passed: 1, Failed: 1}, withoutReruns.Counts)
+}
@@ -12,13 +12,28 @@ import (
 	"time"
 )
 
-func serveHTML(ctx context.Context, pr ParseResult) {
-	loaded := make(chan struct{})
+// serveHTML serves test results and opens them in the browser.
+// When timeline is not finished yet, the page is updated live until all results are available.
+func serveHTML(ctx context.Context, timeline *liveTimeline) {
+	page := func() (string, error) {
+		if pr, charts, ok := timeline.Result(); ok {
+			return render(pr, charts, parallelismFromFlags(), true, false)
+		}
+		return renderLive(false)
+	}
+
+	servePage(ctx, page, map[string]http.HandlerFunc{"GET /events": timeline.eventsHandler}, timeline.Done())
+}
 
-	charts := generateCharts(pr)
+// servePage opens the page in the browser and serves it until it's loaded, or until ctx is done with -keep-running.
+// The server is not stopped before done is closed, so the page can receive updates from handlers.
+func servePage(ctx context.Context, page func() (string, error), handlers map[string]http.HandlerFunc, done <-chan struct{}) {
+	// buffered, so the signal is kept when the page is loaded before done is closed
+	loaded := make(chan struct{}, 1)
 
-	http.HandleFunc("GET /", func(writer http.ResponseWriter, request *http.Request) {
-		rendered, err := render(pr, charts, true)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(writer http.ResponseWriter, request *http.Request) {
+		rendered, err := page()
 		if err != nil {
 			writer.WriteHeader(http.StatusInternalServerError)
 			_, _ = writer.Write([]byte(fmt.Sprintf("Error rendering HTML: %s", err)))
@@ -28,9 +43,12 @@ func serveHTML(ctx context.Context, pr ParseResult) {
 
 		_, _ = writer.Write([]byte(rendered))
 	})
-	http.HandleFunc("GET /loaded", func(writer http.ResponseWriter, request *http.Request) {
+	mux.HandleFunc("GET /loaded", func(writer http.ResponseWriter, request *http.Request) {
 		loadedHandler(writer, request, loaded)
 	})
+	for pattern, handler := range handlers {
+		mux.HandleFunc(pattern, handler)
+	}
 
 	listener, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
@@ -42,7 +60,7 @@ func serveHTML(ctx context.Context, pr ParseResult) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	server := &http.Server{}
+	server := &http.Server{Handler: mux}
 	go func() {
 		err := server.Serve(listener)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -59,6 +77,11 @@ func serveHTML(ctx context.Context, pr ParseResult) {
 		return
 	}
 
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
 	if !keepRunning {
 		select {
 		case <-loaded:
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+const (
+	shardFormatShell = "shell"
+	shardFormatJSON  = "json"
+)
+
+// observedPackage contains durations of a package averaged over all runs in which it was present.
+type observedPackage struct {
+	Duration time.Duration
+	// Setup is the time before the first test, which is paid by every shard running tests of the package.
+	Setup time.Duration
+	// Tests are durations of top-level tests.
+	Tests map[string]time.Duration
+}
+
+func observedDurations(runs []parse.ParseResult) map[string]observedPackage {
+	type sum struct {
+		runs       int
+		duration   time.Duration
+		setup      time.Duration
+		tests      map[string]time.Duration
+		testsCount map[string]int
+	}
+	sums := map[string]*sum{}
+
+	for _, pr := range runs {
+		for pkg, execution := range pr.Packages {
+			s, ok := sums[pkg]
+			if !ok {
+				s = &sum{tests: map[string]time.Duration{}, testsCount: map[string]int{}}
+				sums[pkg] = s
+			}
+
+			duration := execution.Duration()
+			if execution.Elapsed > 0 {
+				duration = execution.Elapsed
+			}
+
+			s.runs++
+			s.duration += duration
+			s.setup += execution.SetupDuration()
+		}
+
+		for _, root := range pr.TestTree() {
+			s, ok := sums[root.Test.Package]
+			if !ok {
+				continue
+			}
+			s.tests[root.Test.TestName] += pr.TestRuns.DurationStats(root.Test).Median
+			s.testsCount[root.Test.TestName]++
+		}
+	}
+
+	packages := map[string]observedPackage{}
+	for pkg, s := range sums {
+		p := observedPackage{
+			Duration: s.duration / time.Duration(s.runs),
+			Setup:    s.setup / time.Duration(s.runs),
+			Tests:    map[string]time.Duration{},
+		}
+		for test, duration := range s.tests {
+			p.Tests[test] = duration / time.Duration(s.testsCount[test])
+		}
+		packages[pkg] = p
+	}
+
+	return packages
+}
+
+// shardPackage is a package run by a shard.
+type shardPackage struct {
+	Package string `json:"package"`
+	// Run selects tests of the package run by the shard, all tests are run if it's empty.
+	Run string `json:"run,omitempty"`
+	// Skip excludes tests run by other shards, so tests which are not known yet are run too.
+	Skip string `json:"skip,omitempty"`
+}
+
+type shard struct {
+	Index     int
+	Estimated time.Duration
+	Packages  []shardPackage
+}
+
+// Commands returns go test command lines running packages of the shard.
+// Packages running all tests are run by one command, packages with -run or -skip by separate commands.
+func (s shard) Commands(goTestFlags string) []string {
+	prefix := "go test"
+	if goTestFlags != "" {
+		prefix += " " + goTestFlags
+	}
+
+	var commands []string
+	var whole []string
+
+	for _, p := range s.Packages {
+		switch {
+		case p.Run != "":
+			commands = append(commands, fmt.Sprintf("%s -run %s %s", prefix, shellQuote(p.Run), shellQuote(p.Package)))
+		case p.Skip != "":
+			commands = append(commands, fmt.Sprintf("%s -skip %s %s", prefix, shellQuote(p.Skip), shellQuote(p.Package)))
+		default:
+			whole = append(whole, shellQuote(p.Package))
+		}
+	}
+
+	if len(whole) > 0 {
+		commands = append([]string{prefix + " " + strings.Join(whole, " ")}, commands...)
+	}
+
+	return commands
+}
+
+// shardUnit is a package or a top-level test of a package assigned to a shard.
+type shardUnit struct {
+	Package string
+	// Test is empty if the whole package is assigned.
+	Test     string
+	Duration time.Duration
+}
+
+// planShards assigns packages to n shards, minimising the duration of the longest shard.
+// Units are assigned from the longest to the least loaded shard.
+// With splitTests, packages longer than the ideal shard are split into their top-level tests.
+func planShards(packages map[string]observedPackage, n int, splitTests bool) []shard {
+	var total time.Duration
+	for _, p := range packages {
+		total += p.Duration
+	}
+	ideal := total / time.Duration(n)
+
+	var units []shardUnit
+	for pkg, p := range packages {
+		if !splitTests || p.Duration <= ideal || len(p.Tests) < 2 {
+			units = append(units, shardUnit{Package: pkg, Duration: p.Duration})
+			continue
+		}
+
+		for test, duration := range p.Tests {
+			units = append(units, shardUnit{Package: pkg, Test: test, Duration: duration})
+		}
+	}
+	sort.Slice(units, func(i, j int) bool {
+		if units[i].Duration != units[j].Duration {
+			return units[i].Duration > units[j].Duration
+		}
+		if units[i].Package != units[j].Package {
+			return units[i].Package < units[j].Package
+		}
+		return units[i].Test < units[j].Test
+	})
+
+	shards := make([]shard, n)
+	// tests of split packages assigned to each shard
+	testsOf := make([]map[string][]string, n)
+	for i := range shards {
+		shards[i].Index = i + 1
+		testsOf[i] = map[string][]string{}
+	}
+
+	for _, unit := range units {
+		cost := func(i int) time.Duration {
+			if _, ok := testsOf[i][unit.Package]; unit.Test != "" && !ok {
+				return unit.Duration + packages[unit.Package].Setup
+			}
+			return unit.Duration
+		}
+
+		best := 0
+		for i := range shards {
+			if shards[i].Estimated+cost(i) < shards[best].Estimated+cost(best) {
+				best = i
+			}
+		}
+
+		shards[best].Estimated += cost(best)
+		if unit.Test == "" {
+			shards[best].Packages = append(shards[best].Packages, shardPackage{Package: unit.Package})
+		} else {
+			testsOf[best][unit.Package] = append(testsOf[best][unit.Package], unit.Test)
+		}
+	}
+
+	splitPackages := map[string][]int{}
+	for i := range shards {
+		for pkg := range testsOf[i] {
+			splitPackages[pkg] = append(splitPackages[pkg], i)
+		}
+	}
+
+	for pkg, shardIndexes := range splitPackages {
+		sort.Ints(shardIndexes)
+
+		if len(shardIndexes) == 1 {
+			i := shardIndexes[0]
+			shards[i].Packages = append(shards[i].Packages, shardPackage{Package: pkg})
+			continue
+		}
+
+		// the first shard runs all tests which are not run by other shards
+		var others []string
+		for _, i := range shardIndexes[1:] {
+			others = append(others, testsOf[i][pkg]...)
+			shards[i].Packages = append(shards[i].Packages, shardPackage{Package: pkg, Run: testsRegexp(testsOf[i][pkg])})
+		}
+		first := shardIndexes[0]
+		shards[first].Packages = append(shards[first].Packages, shardPackage{Package: pkg, Skip: testsRegexp(others)})
+	}
+
+	for i := range shards {
+		sort.Slice(shards[i].Packages, func(a, b int) bool {
+			return shards[i].Packages[a].Package < shards[i].Packages[b].Package
+		})
+	}
+
+	return shards
+}
+
+// testsRegexp returns a -run/-skip pattern matching exactly the top-level tests.
+func testsRegexp(tests []string) string {
+	sorted := make([]string, 0, len(tests))
+	for _, test := range tests {
+		sorted = append(sorted, regexp.QuoteMeta(test))
+	}
+	sort.Strings(sorted)
+
+	return "^(" + strings.Join(sorted, "|") + ")$"
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=@+-]+$`)
+
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+type shardJSON struct {
+	Index            int            `json:"index"`
+	EstimatedSeconds float64        `json:"estimated_seconds"`
+	Packages         []shardPackage `json:"packages"`
+	Commands         []string       `json:"commands"`
+}
+
+func renderShardsJSON(shards []shard, goTestFlags string) ([]byte, error) {
+	out := make([]shardJSON, 0, len(shards))
+	for _, s := range shards {
+		out = append(out, shardJSON{
+			Index:            s.Index,
+			EstimatedSeconds: s.Estimated.Seconds(),
+			Packages:         append([]shardPackage{}, s.Packages...),
+			Commands:         append([]string{}, s.Commands(goTestFlags)...),
+		})
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func writeShardsShell(w io.Writer, shards []shard, goTestFlags string) {
+	for _, s := range shards {
+		_, _ = fmt.Fprintf(w, "# shard %d/%d, estimated %s\n", s.Index, len(shards), s.Estimated.Round(time.Millisecond))
+		for _, command := range s.Commands(goTestFlags) {
+			_, _ = fmt.Fprintln(w, command)
+		}
+	}
+}
+
+// runShard implements "vgt shard -n 8 run.json..." and returns the exit code.
+func runShard(args []string) int {
+	flags := flag.NewFlagSet("shard", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(flags.Output(), "Usage: vgt shard [flags] run.json...")
+		flags.PrintDefaults()
+	}
+
+	var n, index int
+	var splitTests bool
+	var format, goTestFlags string
+
+	flags.BoolVar(&debug, "debug", false, "enable debug mode")
+	flags.IntVar(&n, "n", 2, "number of shards")
+	flags.IntVar(&index, "index", 0, "print only the shard with this index, starting from 1 (0 prints all shards)")
+	flags.BoolVar(&splitTests, "split-tests", false, "split packages longer than the ideal shard into their top-level tests, selected with -run and -skip")
+	flags.StringVar(&format, "format", shardFormatShell, "output format, one of: shell, json")
+	flags.StringVar(&goTestFlags, "go-test-flags", "", "flags added to go test commands, for example \"-json -count=1\"")
+	_ = flags.Parse(args)
+
+	setupLogger()
+
+	if flags.NArg() == 0 || n < 1 || index < 0 || index > n || (format != shardFormatShell && format != shardFormatJSON) {
+		flags.Usage()
+		return 2
+	}
+
+	var runs []parse.ParseResult
+	for _, path := range flags.Args() {
+		pr, err := parseFile(path)
+		if err != nil {
+			slog.Error("Error reading run", "err", err)
+			return 1
+		}
+		runs = append(runs, pr)
+	}
+
+	shards := planShards(observedDurations(runs), n, splitTests)
+	if index > 0 {
+		shards = shards[index-1 : index]
+	}
+
+	if format == shardFormatJSON {
+		out, err := renderShardsJSON(shards, goTestFlags)
+		if err != nil {
+			slog.Error("Error marshalling shards", "err", err)
+			return 1
+		}
+		_, _ = fmt.Fprintln(os.Stdout, string(out))
+		return 0
+	}
+
+	if index > 0 {
+		for _, command := range shards[0].Commands(goTestFlags) {
+			_, _ = fmt.Fprintln(os.Stdout, command)
+		}
+		return 0
+	}
+
+	writeShardsShell(os.Stdout, shards, goTestFlags)
+
+	return 0
+}
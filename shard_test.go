@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanShards(t *testing.T) {
+	packages := map[string]observedPackage{
+		"example.com/a": {Duration: 6 * time.Second},
+		"example.com/b": {Duration: 3 * time.Second},
+		"example.com/c": {Duration: 2 * time.Second},
+		"example.com/d": {Duration: time.Second},
+	}
+
+	shards := planShards(packages, 2, false)
+	require.Len(t, shards, 2)
+	require.Equal(t, 6*time.Second, shards[0].Estimated)
+	require.Equal(t, []shardPackage{{Package: "example.com/a"}}, shards[0].Packages)
+	require.Equal(t, 6*time.Second, shards[1].Estimated)
+	require.Equal(t, []string{"go test -json example.com/b example.com/c example.com/d"}, shards[1].Commands("-json"))
+}
+
+func TestPlanShards_split_tests(t *testing.T) {
+	packages := map[string]observedPackage{
+		"example.com/slow": {
+			Duration: 8 * time.Second,
+			Setup:    100 * time.Millisecond,
+			Tests: map[string]time.Duration{
+				"TestA": 4 * time.Second,
+				"TestB": 3 * time.Second,
+				"TestC": time.Second,
+			},
+		},
+		"example.com/fast": {Duration: 2 * time.Second},
+	}
+
+	shards := planShards(packages, 2, true)
+
+	require.Equal(t, 5100*time.Millisecond, shards[0].Estimated)
+	require.Equal(t, []string{
+		"go test -skip '^(TestB)$' example.com/slow",
+	}, shards[0].Commands(""), "the first shard runs tests which are not known yet")
+
+	require.Equal(t, 5100*time.Millisecond, shards[1].Estimated)
+	require.Equal(t, []string{
+		"go test example.com/fast",
+		"go test -run '^(TestB)$' example.com/slow",
+	}, shards[1].Commands(""))
+}
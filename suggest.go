@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// minParallelSaving is the minimal estimated saving for which t.Parallel is suggested.
+const minParallelSaving = 10 * time.Millisecond
+
+type parallelSuggestion struct {
+	Test     parse.TestName
+	Duration time.Duration
+	// Saving is the estimated reduction of the package's duration if the test called t.Parallel.
+	Saving time.Duration
+}
+
+type scheduledTest struct {
+	Test     parse.TestName
+	Duration time.Duration
+	Parallel bool
+}
+
+// suggestParallelTests returns top-level tests which don't call t.Parallel,
+// ranked by how much time the package would save if they did.
+//
+// Savings are estimated by simulating how go test schedules tests: serial tests run one by one,
+// and parallel tests are released in the order they started when all serial tests are finished,
+// with at most parallel of them running at once.
+// Each suggestion is estimated separately, as if it was the only test changed.
+func suggestParallelTests(pr parse.ParseResult, parallel int) []parallelSuggestion {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	testsByPackage := map[string][]scheduledTest{}
+	for _, root := range pr.TestTree() {
+		runs, _ := pr.TestRuns.ByTestName(root.Test)
+
+		testsByPackage[root.Test.Package] = append(testsByPackage[root.Test.Package], scheduledTest{
+			Test:     root.Test,
+			Duration: pr.TestRuns.DurationStats(root.Test).Median,
+			Parallel: slices.ContainsFunc(runs, func(run parse.TestExecution) bool {
+				return len(run.Pauses()) > 0
+			}),
+		})
+	}
+
+	var suggestions []parallelSuggestion
+
+	for _, tests := range testsByPackage {
+		baseline := simulatePackage(tests, parallel)
+
+		for i, test := range tests {
+			if test.Parallel {
+				continue
+			}
+
+			changed := slices.Clone(tests)
+			changed[i].Parallel = true
+
+			saving := baseline - simulatePackage(changed, parallel)
+			if saving < minParallelSaving {
+				continue
+			}
+
+			suggestions = append(suggestions, parallelSuggestion{
+				Test:     test.Test,
+				Duration: test.Duration,
+				Saving:   saving,
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Saving != suggestions[j].Saving {
+			return suggestions[i].Saving > suggestions[j].Saving
+		}
+		return suggestions[i].Test.String() < suggestions[j].Test.String()
+	})
+
+	return suggestions
+}
+
+// simulatePackage returns the estimated duration of tests in the package.
+func simulatePackage(tests []scheduledTest, parallel int) time.Duration {
+	var serial time.Duration
+	slots := make([]time.Duration, parallel)
+
+	for _, test := range tests {
+		if !test.Parallel {
+			serial += test.Duration
+			continue
+		}
+
+		// the test is started in the slot which is free first
+		free := 0
+		for i := range slots {
+			if slots[i] < slots[free] {
+				free = i
+			}
+		}
+		slots[free] += test.Duration
+	}
+
+	return serial + slices.Max(slots)
+}
+
+func writeParallelSuggestions(w io.Writer, suggestions []parallelSuggestion, limit int) {
+	if len(suggestions) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "\nTests which could call t.Parallel (estimated saving of the package time):\n")
+	for i, s := range suggestions {
+		if i == limit {
+			_, _ = fmt.Fprintf(w, "  ... and %d more\n", len(suggestions)-limit)
+			break
+		}
+
+		_, _ = fmt.Fprintf(
+			w,
+			"  %-70s %10s  saves %s\n",
+			s.Test,
+			s.Duration.Round(time.Millisecond),
+			s.Saving.Round(time.Millisecond),
+		)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestParallelTests(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestSerial"}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestSerial","Elapsed":2}
+{"Time":"2024-09-18T21:02:14.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestShort"}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestShort","Elapsed":0.005}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestP1"}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestP1"}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestP2"}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestP2"}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestP1"}
+{"Time":"2024-09-18T21:02:14.005000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestP2"}
+{"Time":"2024-09-18T21:02:15.005000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestP1","Elapsed":1}
+{"Time":"2024-09-18T21:02:16.005000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestP2","Elapsed":2}
+{"Time":"2024-09-18T21:02:16.000000+02:00","Action":"run","Package":"example.com/other","Test":"TestOnlySerial"}
+{"Time":"2024-09-18T21:02:18.000000+02:00","Action":"pass","Package":"example.com/other","Test":"TestOnlySerial","Elapsed":2}
+`
+	pr := mustParse(t, input)
+
+	suggestions := suggestParallelTests(pr, 4)
+	require.Len(t, suggestions, 1, "short tests and tests which are alone in the package should not be suggested")
+	require.Equal(t, "TestSerial", suggestions[0].Test.TestName)
+	require.Equal(t, 2*time.Second, suggestions[0].Saving)
+
+	suggestions = suggestParallelTests(pr, 1)
+	require.Empty(t, suggestions, "nothing can be saved when tests can't run in parallel")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+const (
+	svgRowHeight     = 20
+	svgHeaderHeight  = 40
+	svgAxisHeight    = 30
+	svgTimelineWidth = 1200
+	svgMaxLabelWidth = 400
+	// svgCharWidth is an approximate width of a character of the 11px font,
+	// there is no way to measure text without a browser.
+	svgCharWidth = 6.5
+)
+
+// renderSVG renders charts as a static Gantt chart, which can be viewed without JavaScript.
+// Rows are presented in the same order as in the HTML view.
+func renderSVG(pr parse.ParseResult, charts []PlotlyChart) string {
+	end := 0.0
+	labelWidth := 0.0
+	for _, ch := range charts {
+		for i := range ch.X {
+			end = math.Max(end, ch.Base[i]+ch.X[i])
+		}
+		if len(ch.Y) > 0 {
+			labelWidth = math.Max(labelWidth, float64(len(ch.Y[0]))*svgCharWidth)
+		}
+	}
+	labelWidth = math.Min(labelWidth, svgMaxLabelWidth) + 10
+
+	if end == 0 {
+		end = 1
+	}
+	scale := svgTimelineWidth / end
+
+	width := labelWidth + svgTimelineWidth + 20
+	height := float64(svgHeaderHeight + len(charts)*svgRowHeight + svgAxisHeight)
+
+	b := new(strings.Builder)
+
+	_, _ = fmt.Fprintf(
+		b,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="sans-serif" font-size="11">`+"\n",
+		width, height, width, height,
+	)
+	_, _ = fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+
+	summary := fmt.Sprintf(
+		"Test Results (%s %d passed, %d failed, %d skipped",
+		pr.End.Sub(pr.Start).Round(time.Millisecond),
+		pr.Counts.Passed,
+		pr.Counts.Failed,
+		pr.Counts.Skipped,
+	)
+	if buildFailed := pr.BuildFailures(); buildFailed > 0 {
+		summary += fmt.Sprintf(", %d build failed", buildFailed)
+	}
+	summary += ")"
+	_, _ = fmt.Fprintf(b, `<text x="10" y="24" font-size="16">%s</text>`+"\n", html.EscapeString(summary))
+
+	axisTop := float64(svgHeaderHeight + len(charts)*svgRowHeight)
+	step := tickStep(end, 10)
+	for tick := 0.0; tick <= end+step/1000; tick += step {
+		x := labelWidth + tick*scale
+		_, _ = fmt.Fprintf(
+			b,
+			`<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#eee"/>`+"\n"+
+				`<text x="%.1f" y="%.1f" text-anchor="middle" fill="#444">%ss</text>`+"\n",
+			x, svgHeaderHeight, x, axisTop,
+			x, axisTop+16, formatSeconds(tick),
+		)
+	}
+
+	for row, ch := range charts {
+		if len(ch.Y) == 0 {
+			continue
+		}
+		top := float64(svgHeaderHeight + row*svgRowHeight)
+
+		labelColor := "#222"
+		if strings.HasSuffix(ch.Y[0], "failed)") {
+			labelColor = failedColor
+		}
+		_, _ = fmt.Fprintf(
+			b,
+			`<text x="%.1f" y="%.1f" text-anchor="end" fill="%s">%s</text>`+"\n",
+			labelWidth-5, top+svgRowHeight*0.7, labelColor, html.EscapeString(truncateLabel(ch.Y[0], int((labelWidth-10)/svgCharWidth))),
+		)
+
+		for i := range ch.X {
+			barHeight := svgRowHeight * ch.Width[i]
+			x := labelWidth + ch.Base[i]*scale
+			barWidth := math.Max(ch.X[i]*scale, 1)
+			y := top + (svgRowHeight-barHeight)/2
+			label := html.EscapeString(ch.Text[i])
+
+			_, _ = fmt.Fprintf(
+				b,
+				`<g><title>%s</title><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`,
+				label, x, y, barWidth, barHeight, ch.Marker.Color[i],
+			)
+			// like in the HTML view, labels are presented only if they fit the bar
+			if float64(len(ch.Text[i]))*svgCharWidth < barWidth-4 {
+				_, _ = fmt.Fprintf(
+					b,
+					`<text x="%.1f" y="%.1f" fill="white">%s</text>`,
+					x+2, top+svgRowHeight*0.7, label,
+				)
+			}
+			_, _ = fmt.Fprint(b, "</g>\n")
+		}
+	}
+
+	_, _ = fmt.Fprint(b, "</svg>\n")
+
+	return b.String()
+}
+
+// tickStep returns a round step between axis ticks, so there are no more than maxTicks of them.
+func tickStep(end float64, maxTicks int) float64 {
+	magnitude := math.Pow(10, math.Floor(math.Log10(end/float64(maxTicks))))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if end/(magnitude*m) <= float64(maxTicks) {
+			return magnitude * m
+		}
+	}
+
+	return magnitude * 10
+}
+
+func formatSeconds(s float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", s), "0"), ".")
+}
+
+func truncateLabel(label string, maxChars int) string {
+	runes := []rune(label)
+	if len(runes) <= maxChars || maxChars < 1 {
+		return label
+	}
+
+	// the end of the name is more specific than the package
+	return "…" + string(runes[len(runes)-maxChars+1:])
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSVG(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB/a<b"}
+{"Time":"2024-09-18T21:02:12.900000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestB/a<b"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"fail","Package":"example.com/pkg"}
+`
+	pr := mustParse(t, input)
+
+	svg := renderSVG(pr, generateCharts(pr))
+
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	rects := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err, "svg should be valid xml")
+
+		if el, ok := token.(xml.StartElement); ok && el.Name.Local == "rect" {
+			rects++
+		}
+	}
+
+	// background, package setup, package, and two tests
+	require.Equal(t, 5, rects)
+	require.Contains(t, svg, "pkg.TestB/a&lt;b (failed)")
+	require.Contains(t, svg, `fill="`+failedColor+`"`)
+	require.Contains(t, svg, "1 passed, 1 failed, 0 skipped")
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// traceEvent is an event of the Trace Event Format, which can be opened in Perfetto or chrome://tracing.
+// See https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type traceEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// packageTid is the thread of the package execution, tests are on the following threads.
+const packageTid = 0
+
+// renderChromeTrace converts results to the Trace Event Format.
+// Each package is a process, and tests are slices on threads ("lanes")
+// assigned in a way that concurrent tests don't overlap.
+func renderChromeTrace(pr parse.ParseResult) ([]byte, error) {
+	ts := func(t time.Time) float64 {
+		return float64(t.Sub(pr.Start).Nanoseconds()) / 1000
+	}
+	dur := func(d time.Duration) float64 {
+		return float64(d.Nanoseconds()) / 1000
+	}
+
+	var events []traceEvent
+
+	testsByPackage := map[string][]parse.TestExecution{}
+	for _, execution := range pr.TestRuns.AsSlice() {
+		testsByPackage[execution.Test.Package] = append(testsByPackage[execution.Test.Package], execution)
+	}
+
+	for i, pkg := range pr.PackagesOrderedByStart() {
+		pid := i + 1
+
+		events = append(events, traceEvent{
+			Name: "process_name",
+			Ph:   "M",
+			Pid:  pid,
+			Args: map[string]any{"name": pkg},
+		}, traceEvent{
+			Name: "process_sort_index",
+			Ph:   "M",
+			Pid:  pid,
+			Args: map[string]any{"sort_index": pid},
+		}, traceEvent{
+			Name: "thread_name",
+			Ph:   "M",
+			Pid:  pid,
+			Tid:  packageTid,
+			Args: map[string]any{"name": "package"},
+		})
+
+		if execution, ok := pr.Packages[pkg]; ok {
+			events = append(events, packageTraceEvents(execution, pid, ts, dur)...)
+		}
+
+		executions := testsByPackage[pkg]
+		sort.Slice(executions, func(i, j int) bool {
+			if !executions[i].Start.Equal(executions[j].Start) {
+				return executions[i].Start.Before(executions[j].Start)
+			}
+			if executions[i].Test != executions[j].Test {
+				return executions[i].Test.TestName < executions[j].Test.TestName
+			}
+			return executions[i].Attempt < executions[j].Attempt
+		})
+		lanes := assignLanes(executions)
+
+		for lane := 1; lane <= maxLane(lanes); lane++ {
+			events = append(events, traceEvent{
+				Name: "thread_name",
+				Ph:   "M",
+				Pid:  pid,
+				Tid:  lane,
+				Args: map[string]any{"name": fmt.Sprintf("tests %d", lane)},
+			})
+		}
+
+		for j, execution := range executions {
+			args := map[string]any{
+				"status":  execution.Status,
+				"attempt": execution.Attempt,
+			}
+			if execution.Elapsed > 0 {
+				args["elapsed"] = execution.Elapsed.String()
+			}
+
+			for _, segment := range execution.Segments {
+				name := execution.Test.TestName
+				cat := "test"
+				if segment.Kind == parse.SegmentKindPause {
+					name += " (paused)"
+					cat = "pause"
+				}
+
+				events = append(events, traceEvent{
+					Name: name,
+					Cat:  cat,
+					Ph:   "X",
+					Ts:   ts(segment.Start),
+					Dur:  dur(segment.Duration()),
+					Pid:  pid,
+					Tid:  lanes[j],
+					Args: args,
+				})
+			}
+		}
+	}
+
+	return json.Marshal(traceFile{
+		TraceEvents:     events,
+		DisplayTimeUnit: "ms",
+	})
+}
+
+func packageTraceEvents(
+	pkg parse.PackageExecution,
+	pid int,
+	ts func(time.Time) float64,
+	dur func(time.Duration) float64,
+) []traceEvent {
+	args := map[string]any{
+		"status": pkg.Status,
+	}
+	if pkg.BuildFailed {
+		args["build_failed"] = true
+	}
+
+	events := []traceEvent{{
+		Name: pkg.Package,
+		Cat:  "package",
+		Ph:   "X",
+		Ts:   ts(pkg.Start),
+		Dur:  dur(pkg.Duration()),
+		Pid:  pid,
+		Tid:  packageTid,
+		Args: args,
+	}}
+
+	if setup := pkg.SetupDuration(); setup > 0 {
+		events = append(events, traceEvent{
+			Name: "setup",
+			Cat:  "package",
+			Ph:   "X",
+			Ts:   ts(pkg.Start),
+			Dur:  dur(setup),
+			Pid:  pid,
+			Tid:  packageTid,
+		})
+	}
+
+	return events
+}
+
+// assignLanes returns a lane (starting from 1) for each execution,
+// so executions in the same lane don't overlap.
+func assignLanes(executions []parse.TestExecution) []int {
+	order := make([]int, len(executions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return executions[order[i]].Start.Before(executions[order[j]].Start)
+	})
+
+	lanes := make([]int, len(executions))
+	var laneEnds []time.Time
+
+	for _, i := range order {
+		execution := executions[i]
+
+		lane := -1
+		for l, end := range laneEnds {
+			if !end.After(execution.Start) {
+				lane = l
+				break
+			}
+		}
+		if lane == -1 {
+			laneEnds = append(laneEnds, time.Time{})
+			lane = len(laneEnds) - 1
+		}
+
+		laneEnds[lane] = execution.End
+		lanes[i] = lane + 1
+	}
+
+	return lanes
+}
+
+func maxLane(lanes []int) int {
+	m := 0
+	for _, lane := range lanes {
+		m = max(m, lane)
+	}
+
+	return m
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderChromeTrace(t *testing.T) {
+	input := `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.200000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.300000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.500000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:12.600000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:12.700000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestC"}
+{"Time":"2024-09-18T21:02:12.800000+02:00","Action":"pass","Package":"example.com/pkg"}
+`
+
+	trace, err := renderChromeTrace(mustParse(t, input))
+	require.NoError(t, err)
+
+	var parsed traceFile
+	require.NoError(t, json.Unmarshal(trace, &parsed))
+
+	lanes := map[string]int{}
+	slices := map[string]int{}
+
+	for _, event := range parsed.TraceEvents {
+		require.Equal(t, 1, event.Pid)
+
+		if event.Ph != "X" || event.Cat == "package" {
+			continue
+		}
+		lanes[event.Name] = event.Tid
+		slices[event.Cat]++
+	}
+
+	// TestA and TestB were running concurrently
+	require.NotEqual(t, lanes["TestA"], lanes["TestB"])
+	// TestC started after TestA finished, so it can reuse its lane
+	require.Equal(t, lanes["TestA"], lanes["TestC"])
+
+	require.Equal(t, 2, slices["pause"])
+	require.Equal(t, 5, slices["test"])
+}
@@ -0,0 +1,594 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// defaultTerminalWidth is used when the width of the terminal can't be detected, for example in CI.
+const defaultTerminalWidth = 120
+
+// barBlocks are left-aligned blocks, from 1/8 to a full cell.
+var barBlocks = []rune("▏▎▍▌▋▊▉█")
+
+type terminalBar struct {
+	start, end float64
+	color      string
+}
+
+func (b terminalBar) isPause() bool {
+	return b.color == pauseColor
+}
+
+// terminalRow is a row of the timeline, built from the same data as bars in the browser.
+type terminalRow struct {
+	label string
+	key   string
+	bars  []terminalBar
+}
+
+func (r terminalRow) failed() bool {
+	return strings.HasSuffix(r.label, "failed)")
+}
+
+func terminalRows(charts []PlotlyChart) []terminalRow {
+	rows := make([]terminalRow, 0, len(charts))
+
+	for _, ch := range charts {
+		if len(ch.Y) == 0 {
+			continue
+		}
+
+		row := terminalRow{
+			label: ch.Y[0],
+			key:   ch.Customdata[0],
+		}
+		for i := range ch.X {
+			row.bars = append(row.bars, terminalBar{
+				start: ch.Base[i],
+				end:   ch.Base[i] + ch.X[i],
+				color: ch.Marker.Color[i],
+			})
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func rowsEnd(rows []terminalRow) float64 {
+	end := 0.0
+	for _, row := range rows {
+		for _, bar := range row.bars {
+			end = math.Max(end, bar.end)
+		}
+	}
+	if end == 0 {
+		end = 1
+	}
+
+	return end
+}
+
+// terminalTimeline draws rows between from and to seconds.
+type terminalTimeline struct {
+	from, to   float64
+	width      int
+	labelWidth int
+	color      bool
+}
+
+func newTerminalTimeline(rows []terminalRow, from, to float64, width int, color bool) terminalTimeline {
+	labelWidth := 0
+	for _, row := range rows {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(row.label))
+	}
+
+	return terminalTimeline{
+		from:       from,
+		to:         to,
+		width:      width,
+		labelWidth: min(labelWidth, width/3),
+		color:      color,
+	}
+}
+
+func (t terminalTimeline) cells() int {
+	return max(t.width-t.labelWidth-1, 1)
+}
+
+func (t terminalTimeline) axis() string {
+	cells := t.cells()
+	line := []rune(strings.Repeat(" ", cells))
+
+	span := t.to - t.from
+	step := tickStep(span, max(cells/10, 1))
+	lastEnd := -1
+
+	for tick := math.Ceil(t.from/step) * step; tick <= t.to; tick += step {
+		pos := int((tick - t.from) / span * float64(cells))
+		label := []rune("|" + formatSeconds(tick) + "s")
+		if pos <= lastEnd || pos+len(label) > cells {
+			continue
+		}
+
+		copy(line[pos:], label)
+		lastEnd = pos + len(label)
+	}
+
+	return strings.Repeat(" ", t.labelWidth+1) + string(line)
+}
+
+func (t terminalTimeline) row(row terminalRow) string {
+	b := new(strings.Builder)
+
+	label := truncateLabel(row.label, t.labelWidth)
+	label = strings.Repeat(" ", t.labelWidth-utf8.RuneCountInString(label)) + label
+	if t.color && row.failed() {
+		label = ansiColor(failedColor) + label + ansiReset
+	}
+	b.WriteString(label)
+	b.WriteString(" ")
+
+	cells := t.cells()
+	cellDuration := (t.to - t.from) / float64(cells)
+
+	coverage := make([]float64, cells)
+	covering := make([]terminalBar, cells)
+	// bars starting in the middle of a cell are aligned to the right
+	rightAligned := make([]bool, cells)
+
+	for _, bar := range row.bars {
+		start := (bar.start - t.from) / cellDuration
+		end := (bar.end - t.from) / cellDuration
+		if end <= 0 || start >= float64(cells) {
+			continue
+		}
+
+		for c := max(int(start), 0); c < min(int(math.Ceil(end)), cells); c++ {
+			covered := math.Min(end, float64(c+1)) - math.Max(start, float64(c))
+			// very short bars are still presented, like in the browser
+			if covered > coverage[c] || coverage[c] == 0 {
+				coverage[c] = math.Max(covered, 0.01)
+				covering[c] = bar
+				rightAligned[c] = start > float64(c) && end >= float64(c+1)
+			}
+		}
+	}
+
+	currentColor := ""
+	for c := range cells {
+		if coverage[c] == 0 {
+			if currentColor != "" {
+				b.WriteString(ansiReset)
+				currentColor = ""
+			}
+			b.WriteString(" ")
+			continue
+		}
+
+		bar := covering[c]
+		if t.color && bar.color != currentColor {
+			b.WriteString(ansiColor(bar.color))
+			currentColor = bar.color
+		}
+
+		if bar.isPause() && !t.color {
+			b.WriteString("░")
+			continue
+		}
+
+		if rightAligned[c] {
+			if coverage[c] >= 0.5 {
+				b.WriteString("▐")
+			} else {
+				b.WriteString("▕")
+			}
+			continue
+		}
+
+		eighths := min(max(int(math.Round(coverage[c]*8)), 1), 8)
+		b.WriteRune(barBlocks[eighths-1])
+	}
+	if currentColor != "" {
+		b.WriteString(ansiReset)
+	}
+
+	return b.String()
+}
+
+const ansiReset = "\x1b[0m"
+
+// ansiColor converts a chart color like "rgba(255, 0, 0, 100)" to a 24-bit foreground color.
+func ansiColor(color string) string {
+	color = strings.TrimPrefix(color, "rgba(")
+	color = strings.TrimSuffix(color, ")")
+
+	parts := strings.Split(color, ",")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	rgb := make([]string, 3)
+	for i := range rgb {
+		rgb[i] = strings.TrimSpace(parts[i])
+	}
+
+	return fmt.Sprintf("\x1b[38;2;%sm", strings.Join(rgb, ";"))
+}
+
+func summaryLine(pr parse.ParseResult) string {
+	summary := fmt.Sprintf(
+		"%s: %d passed, %d failed, %d skipped",
+		pr.End.Sub(pr.Start).Round(time.Millisecond),
+		pr.Counts.Passed,
+		pr.Counts.Failed,
+		pr.Counts.Skipped,
+	)
+	if buildFailed := pr.BuildFailures(); buildFailed > 0 {
+		summary += fmt.Sprintf(", %d build failed", buildFailed)
+	}
+
+	return summary
+}
+
+// renderASCII renders the whole timeline scaled to the given width.
+func renderASCII(pr parse.ParseResult, charts []PlotlyChart, width int, color bool) string {
+	rows := terminalRows(charts)
+	t := newTerminalTimeline(rows, 0, rowsEnd(rows), width, color)
+
+	lines := []string{summaryLine(pr), t.axis()}
+	for _, row := range rows {
+		lines = append(lines, t.row(row))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// tuiView is the state of the interactive timeline.
+type tuiView struct {
+	summary string
+	rows    []terminalRow
+	end     float64
+
+	from, to float64
+	offset   int
+
+	filter    string
+	filtering bool
+}
+
+func newTUIView(pr parse.ParseResult, charts []PlotlyChart) *tuiView {
+	rows := terminalRows(charts)
+	end := rowsEnd(rows)
+
+	return &tuiView{
+		summary: summaryLine(pr),
+		rows:    rows,
+		end:     end,
+		to:      end,
+	}
+}
+
+// visibleRows returns rows matching the filter, which is matched against package and test names.
+func (v *tuiView) visibleRows() []terminalRow {
+	if v.filter == "" {
+		return v.rows
+	}
+
+	filter := strings.ToLower(v.filter)
+
+	var rows []terminalRow
+	for _, row := range v.rows {
+		if strings.Contains(strings.ToLower(row.key), filter) {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// handleKey updates the view, it returns false when the user wants to quit.
+func (v *tuiView) handleKey(key string, pageSize int) bool {
+	if v.filtering {
+		switch key {
+		case "enter":
+			v.filtering = false
+		case "esc":
+			v.filter = ""
+			v.filtering = false
+		case "backspace":
+			if v.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(v.filter)
+				v.filter = v.filter[:len(v.filter)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				v.filter += key
+			}
+		}
+		v.offset = 0
+
+		return true
+	}
+
+	span := v.to - v.from
+
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "up", "k":
+		v.offset--
+	case "down", "j":
+		v.offset++
+	case "pgup":
+		v.offset -= pageSize
+	case "pgdown", " ":
+		v.offset += pageSize
+	case "left", "h":
+		v.pan(-span / 4)
+	case "right", "l":
+		v.pan(span / 4)
+	case "+", "=":
+		v.zoom(0.5)
+	case "-":
+		v.zoom(2)
+	case "0":
+		v.from, v.to = 0, v.end
+	case "/":
+		v.filtering = true
+	case "esc":
+		v.filter = ""
+	}
+
+	v.offset = max(min(v.offset, len(v.visibleRows())-pageSize), 0)
+
+	return true
+}
+
+func (v *tuiView) pan(by float64) {
+	span := v.to - v.from
+	v.from = math.Min(math.Max(v.from+by, 0), v.end-span)
+	v.to = v.from + span
+}
+
+func (v *tuiView) zoom(factor float64) {
+	center := (v.from + v.to) / 2
+	// bars are rounded to 10ms, so it doesn't make sense to zoom in more
+	span := math.Min(math.Max((v.to-v.from)*factor, 0.05), v.end)
+
+	v.from = math.Max(center-span/2, 0)
+	v.to = v.from + span
+	v.pan(0)
+}
+
+func (v *tuiView) render(width, height int, color bool) string {
+	rows := v.visibleRows()
+	pageSize := max(height-3, 1)
+
+	t := newTerminalTimeline(rows, v.from, v.to, width, color)
+
+	header := v.summary
+	if v.filter != "" || v.filtering {
+		header += fmt.Sprintf("  filter: %s", v.filter)
+		if v.filtering {
+			header += "_"
+		}
+	}
+
+	lines := []string{header, t.axis()}
+	for i := v.offset; i < min(v.offset+pageSize, len(rows)); i++ {
+		lines = append(lines, t.row(rows[i]))
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, fmt.Sprintf(
+		"rows %d-%d of %d  ↑↓ scroll  ←→ pan  +/- zoom  0 reset  / filter  q quit",
+		min(v.offset+1, len(rows)),
+		min(v.offset+pageSize, len(rows)),
+		len(rows),
+	))
+
+	return strings.Join(lines, "\r\n")
+}
+
+// runTUI presents the interactive timeline until the user quits.
+// Keys are read from the terminal, so it works when test output is piped to vgt.
+func runTUI(ctx context.Context, pr parse.ParseResult, charts []PlotlyChart) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("interactive mode requires a terminal: %w", err)
+	}
+	defer tty.Close()
+
+	state, err := stty(tty, "-g")
+	if err != nil {
+		return err
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = stty(tty, state)
+	}()
+
+	// alternate screen keeps the terminal history untouched
+	_, _ = fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		_, _ = fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+	}()
+
+	keys := make(chan string)
+	go readKeys(tty, keys)
+
+	view := newTUIView(pr, charts)
+	color := os.Getenv("NO_COLOR") == ""
+
+	width, height := terminalSize(tty)
+	draw := func() {
+		_, _ = fmt.Fprint(tty, "\x1b[H\x1b[2J"+view.render(width, height, color))
+	}
+	draw()
+
+	resizeCheck := time.NewTicker(500 * time.Millisecond)
+	defer resizeCheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok || !view.handleKey(key, max(height-3, 1)) {
+				return nil
+			}
+			draw()
+		case <-resizeCheck.C:
+			w, h := terminalSize(tty)
+			if w != width || h != height {
+				width, height = w, h
+				draw()
+			}
+		}
+	}
+}
+
+func readKeys(tty *os.File, keys chan<- string) {
+	defer close(keys)
+
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+var escapeSequences = map[string]string{
+	"\x1b[A":  "up",
+	"\x1b[B":  "down",
+	"\x1b[C":  "right",
+	"\x1b[D":  "left",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+}
+
+func parseKeys(input []byte) []string {
+	var keys []string
+
+	s := string(input)
+	for len(s) > 0 {
+		if s[0] == '\x1b' {
+			matched := false
+			for seq, key := range escapeSequences {
+				if strings.HasPrefix(s, seq) {
+					keys = append(keys, key)
+					s = s[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				keys = append(keys, "esc")
+				s = s[1:]
+			}
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 127, '\b':
+			keys = append(keys, "backspace")
+		case 3:
+			keys = append(keys, "ctrl-c")
+		default:
+			keys = append(keys, string(r))
+		}
+	}
+
+	return keys
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error running stty %s: %w", strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// terminalSize returns the width and height of the terminal, or defaults if they can't be detected.
+func terminalSize(tty *os.File) (int, int) {
+	width, height := defaultTerminalWidth, 40
+
+	if tty == nil {
+		return width, height
+	}
+
+	size, err := stty(tty, "size")
+	if err != nil {
+		return width, height
+	}
+
+	var rows, cols int
+	if _, err := fmt.Sscanf(size, "%d %d", &rows, &cols); err != nil || rows == 0 || cols == 0 {
+		return width, height
+	}
+
+	return cols, rows
+}
+
+// asciiWidth returns the width for -ascii output, $COLUMNS takes precedence over the terminal's width.
+func asciiWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return defaultTerminalWidth
+	}
+	defer tty.Close()
+
+	width, _ := terminalSize(tty)
+
+	return width
+}
+
+// asciiColor returns true if -ascii output should be colored.
+func asciiColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
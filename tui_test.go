@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+const tuiTestInput = `{"Time":"2024-09-18T21:02:12.000000+02:00","Action":"start","Package":"example.com/pkg"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.100000+02:00","Action":"pause","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:12.500000+02:00","Action":"cont","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Time":"2024-09-18T21:02:13.000000+02:00","Action":"run","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:13.500000+02:00","Action":"fail","Package":"example.com/pkg","Test":"TestB"}
+{"Time":"2024-09-18T21:02:13.600000+02:00","Action":"fail","Package":"example.com/pkg"}
+`
+
+func TestRenderASCII(t *testing.T) {
+	pr := mustParse(t, tuiTestInput)
+
+	out := renderASCII(pr, generateCharts(pr), 80, false)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+	require.Equal(t, "1.6s: 1 passed, 1 failed, 0 skipped", lines[0])
+	require.Len(t, lines, 5, "summary, axis, package and two tests")
+
+	for _, line := range lines[1:] {
+		require.LessOrEqual(t, utf8.RuneCountInString(line), 80)
+	}
+
+	require.True(t, strings.HasSuffix(strings.Fields(lines[2])[0], "example.com/pkg"))
+	require.Contains(t, lines[3], "pkg.TestA")
+	require.Contains(t, lines[3], "░", "pause should be presented without colors")
+	require.Contains(t, lines[4], "pkg.TestB (failed)")
+	require.Contains(t, lines[4], "█")
+}
+
+func TestTUIView_handleKey(t *testing.T) {
+	pr := mustParse(t, tuiTestInput)
+	view := newTUIView(pr, generateCharts(pr))
+
+	require.True(t, view.handleKey("+", 10))
+	require.InDelta(t, view.end/2, view.to-view.from, 0.001)
+
+	for range 5 {
+		require.True(t, view.handleKey("right", 10))
+	}
+	require.InDelta(t, view.end, view.to, 0.001, "panning should stop at the end")
+
+	require.True(t, view.handleKey("0", 10))
+	require.Equal(t, 0.0, view.from)
+	require.Equal(t, view.end, view.to)
+
+	for _, key := range parseKeys([]byte("/testb\r")) {
+		require.True(t, view.handleKey(key, 10))
+	}
+	require.False(t, view.filtering)
+	require.Len(t, view.visibleRows(), 1)
+
+	require.True(t, view.handleKey("esc", 10))
+	require.Len(t, view.visibleRows(), 3)
+
+	require.False(t, view.handleKey("q", 10))
+}
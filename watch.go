@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roblaszczak/vgt/parse"
+)
+
+// watchInterval is how often files are checked for changes.
+const watchInterval = 500 * time.Millisecond
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileWatcher detects changes of files which can affect tests: Go files, go.mod, go.sum and files in testdata.
+// Files are polled, so no platform-specific notifications are needed.
+type fileWatcher struct {
+	root  string
+	files map[string]fileState
+}
+
+func newFileWatcher(root string) (*fileWatcher, error) {
+	w := &fileWatcher{root: root}
+
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+
+	return w, nil
+}
+
+func watchedFile(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, ".go") || name == "go.mod" || name == "go.sum" {
+		return true
+	}
+
+	return strings.Contains(filepath.ToSlash(path), "/testdata/")
+}
+
+func (w *fileWatcher) scan() (map[string]fileState, error) {
+	files := map[string]fileState{}
+
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// files can be removed while walking
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != w.root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !watchedFile(path) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %s: %w", w.root, err)
+	}
+
+	return files, nil
+}
+
+// Changes returns files which were changed, added or removed since the last call.
+func (w *fileWatcher) Changes() ([]string, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for path, state := range files {
+		if previous, ok := w.files[path]; !ok || previous != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+
+	w.files = files
+
+	return changed, nil
+}
+
+// Wait blocks until files are changed, it returns false if ctx is done.
+func (w *fileWatcher) Wait(ctx context.Context) ([]string, bool) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-ticker.C:
+		}
+
+		changed, err := w.Changes()
+		if err != nil {
+			slog.Error("Error watching files", "err", err)
+			continue
+		}
+		if len(changed) > 0 {
+			return changed, true
+		}
+	}
+}
+
+// goModule returns the root directory and the path of the module in the working directory.
+func goModule() (string, string, error) {
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("error running go env: %w", err)
+	}
+
+	goMod := strings.TrimSpace(string(out))
+	if goMod == "" || goMod == os.DevNull {
+		return "", "", fmt.Errorf("not in a Go module")
+	}
+
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", "", fmt.Errorf("error opening go.mod: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return filepath.Dir(goMod), strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+
+	return "", "", fmt.Errorf("module path not found in %s", goMod)
+}
+
+// changedPackages returns import paths of packages containing changed files.
+// It returns false if all packages should be re-tested, because go.mod or go.sum was changed.
+func changedPackages(root, modulePath string, files []string) (map[string]bool, bool) {
+	packages := map[string]bool{}
+
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "go.mod" || rel == "go.sum" {
+			return nil, false
+		}
+
+		dir := rel
+		if i := strings.Index("/"+dir, "/testdata/"); i >= 0 {
+			// files in testdata are used by tests of the package containing testdata
+			dir = strings.TrimSuffix(dir[:i], "/")
+		} else {
+			dir = strings.TrimSuffix(filepath.ToSlash(filepath.Dir(rel)), ".")
+			dir = strings.TrimSuffix(dir, "/")
+		}
+
+		if dir == "" {
+			packages[modulePath] = true
+		} else {
+			packages[modulePath+"/"+dir] = true
+		}
+	}
+
+	return packages, true
+}
+
+// affectedPackages returns packages from go list output which contain changes or depend on changed packages.
+// Each line of the output contains the import path of a package followed by its dependencies,
+// test variants of packages are listed with the import path of the tested package.
+func affectedPackages(goList string, changed map[string]bool) []string {
+	var affected []string
+
+	for _, line := range strings.Split(goList, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		for _, pkg := range fields {
+			if changed[pkg] {
+				affected = append(affected, fields[0])
+				break
+			}
+		}
+	}
+	sort.Strings(affected)
+
+	// packages are listed also as their test variants
+	return slices.Compact(affected)
+}
+
+// listPackages lists packages with their dependencies in the format expected by affectedPackages.
+// With -test, go list lists also test variants of packages, which depend on all packages imported by tests,
+// including transitive dependencies of test-only imports. Generated test main packages are skipped.
+func listPackages(ctx context.Context, patterns []string) (string, error) {
+	args := []string{
+		"list",
+		"-e",
+		"-test",
+		"-f", `{{if or .ForTest (ne .Name "main")}}{{with .ForTest}}{{.}}{{else}}{{.ImportPath}}{{end}} {{join .Deps " "}}{{end}}`,
+	}
+	out, err := exec.CommandContext(ctx, "go", append(args, patterns...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("error running go list: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// watchServer serves the timeline of the latest run, the page is reloaded when a new run starts.
+type watchServer struct {
+	lock     sync.Mutex
+	timeline *liveTimeline
+	// started is closed when a new run starts
+	started chan struct{}
+}
+
+func newWatchServer() *watchServer {
+	return &watchServer{
+		timeline: newLiveTimeline(),
+		started:  make(chan struct{}),
+	}
+}
+
+// Start returns the timeline of a new run.
+func (s *watchServer) Start() *liveTimeline {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.timeline = newLiveTimeline()
+	close(s.started)
+	s.started = make(chan struct{})
+
+	return s.timeline
+}
+
+func (s *watchServer) current() (*liveTimeline, chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.timeline, s.started
+}
+
+func (s *watchServer) page() (string, error) {
+	timeline, _ := s.current()
+	if pr, charts, ok := timeline.Result(); ok {
+		return render(pr, charts, parallelismFromFlags(), false, true)
+	}
+
+	return renderLive(true)
+}
+
+func (s *watchServer) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	timeline, _ := s.current()
+	timeline.eventsHandler(w, r)
+}
+
+// runsHandler notifies the page that a new run started, so it can reload.
+func (s *watchServer) runsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	_, started := s.current()
+
+	select {
+	case <-started:
+		_, _ = fmt.Fprint(w, "event: run\ndata: {}\n\n")
+		flusher.Flush()
+	case <-r.Context().Done():
+	}
+}
+
+// runWatch runs tests, and re-runs tests of affected packages when files are changed, until ctx is done.
+func runWatch(ctx context.Context, args []string) int {
+	root, modulePath, err := goModule()
+	if err != nil {
+		slog.Error("Can't watch files", "err", err)
+		return 1
+	}
+
+	watcher, err := newFileWatcher(root)
+	if err != nil {
+		slog.Error("Can't watch files", "err", err)
+		return 1
+	}
+
+	flags, patterns := splitGoTestArgs(args)
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	server := newWatchServer()
+	go servePage(
+		ctx,
+		server.page,
+		map[string]http.HandlerFunc{
+			"GET /events": server.eventsHandler,
+			"GET /watch":  server.runsHandler,
+		},
+		ctx.Done(),
+	)
+
+	packages := patterns
+	for {
+		runWatched(ctx, server.Start(), goTestArgs(flags, packages))
+
+		for {
+			changed, ok := watcher.Wait(ctx)
+			if !ok {
+				return 0
+			}
+			slog.Info("Files changed", "files", changed)
+
+			packages = packagesToTest(ctx, root, modulePath, patterns, changed)
+			if len(packages) > 0 {
+				break
+			}
+			slog.Info("Changes don't affect tested packages, waiting for more changes")
+		}
+	}
+}
+
+// packagesToTest returns packages affected by changed files.
+func packagesToTest(ctx context.Context, root, modulePath string, patterns []string, changed []string) []string {
+	changedPkgs, ok := changedPackages(root, modulePath, changed)
+	if !ok {
+		return patterns
+	}
+
+	goList, err := listPackages(ctx, patterns)
+	if err != nil {
+		slog.Error("Error finding affected packages, running all tests", "err", err)
+		return patterns
+	}
+
+	return affectedPackages(goList, changedPkgs)
+}
+
+// goTestArgs returns go test arguments with packages, which have to be passed before -args.
+func goTestArgs(flags []string, packages []string) []string {
+	end := len(flags)
+	for i, flag := range flags {
+		if flag == "-args" || flag == "--args" {
+			end = i
+			break
+		}
+	}
+
+	args := append(slices.Clone(flags[:end]), packages...)
+
+	return append(args, flags[end:]...)
+}
+
+func runWatched(ctx context.Context, timeline *liveTimeline, args []string) {
+	runner := newGoTestRunner(ctx, args, rerunFails)
+
+	var passOutput io.Writer
+	if !dontPassOutput {
+		passOutput = os.Stderr
+	}
+
+	fullResult, err := parse.Parse(runner, parse.Options{
+		Output:            passOutput,
+		OnUpdate:          timeline.Update,
+		KeepAllExecutions: true,
+		Reruns:            rerunFails > 0,
+	})
+	if err != nil {
+		slog.Error("Error parsing test output", "err", err)
+	}
+	runner.Close()
+
+	timeline.Finish(fullResult.WithDurationCutoff(testDurationCutoffDuration))
+
+	if ctx.Err() == nil {
+		slog.Info("Tests finished, waiting for changes", "summary", summaryLine(fullResult))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWatcher(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg", "testdata"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", "a.go"), []byte("package pkg"), 0644))
+
+	watcher, err := newFileWatcher(root)
+	require.NoError(t, err)
+
+	changed, err := watcher.Changes()
+	require.NoError(t, err)
+	require.Empty(t, changed)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", "a.go"), []byte("package pkg // changed"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", "testdata", "input.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("not watched"), 0644))
+
+	changed, err = watcher.Changes()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(root, "pkg", "a.go"),
+		filepath.Join(root, "pkg", "testdata", "input.json"),
+	}, changed)
+
+	packages, ok := changedPackages(root, "example.com/mod", changed)
+	require.True(t, ok)
+	require.Equal(t, map[string]bool{"example.com/mod/pkg": true}, packages)
+
+	_, ok = changedPackages(root, "example.com/mod", []string{filepath.Join(root, "go.mod")})
+	require.False(t, ok, "all packages are affected by go.mod")
+}
+
+func TestAffectedPackages(t *testing.T) {
+	goList := `example.com/mod fmt example.com/mod/internal
+example.com/mod/internal fmt
+example.com/mod/other fmt  example.com/mod/testutil
+`
+	require.Equal(
+		t,
+		[]string{"example.com/mod", "example.com/mod/internal"},
+		affectedPackages(goList, map[string]bool{"example.com/mod/internal": true}),
+	)
+	require.Equal(
+		t,
+		[]string{"example.com/mod/other"},
+		affectedPackages(goList, map[string]bool{"example.com/mod/testutil": true}),
+		"test imports are dependencies too",
+	)
+}
+
+func TestListPackages_transitive_test_imports(t *testing.T) {
+	files := map[string]string{
+		"go.mod":              "module example.com/mod\n\ngo 1.22\n",
+		"leaf/leaf.go":        "package leaf\n\nfunc Leaf() int { return 1 }\n",
+		"helper/helper.go":    "package helper\n\nimport \"example.com/mod/leaf\"\n\nfunc Helper() int { return leaf.Leaf() }\n",
+		"pkg/pkg.go":          "package pkg\n",
+		"pkg/pkg_test.go":     "package pkg\n\nimport (\n\t\"testing\"\n\n\t\"example.com/mod/helper\"\n)\n\nfunc TestPkg(t *testing.T) { _ = helper.Helper() }\n",
+		"other/other.go":      "package other\n",
+		"other/other_test.go": "package other_test\n\nimport \"testing\"\n\nfunc TestOther(t *testing.T) {}\n",
+	}
+
+	root := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+
+	goList, err := listPackages(context.Background(), []string{"./..."})
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		[]string{"example.com/mod/helper", "example.com/mod/leaf", "example.com/mod/pkg"},
+		affectedPackages(goList, map[string]bool{"example.com/mod/leaf": true}),
+		"pkg's test imports helper, which depends on leaf",
+	)
+}
+
+func TestGoTestArgs(t *testing.T) {
+	flags, packages := splitGoTestArgs([]string{"-v", "./...", "-run", "TestA", "-args", "-x"})
+	require.Equal(t, []string{"./..."}, packages)
+	require.Equal(t, []string{"-v", "-run", "TestA", "example.com/a", "-args", "-x"}, goTestArgs(flags, []string{"example.com/a"}))
+}